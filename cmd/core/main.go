@@ -3,6 +3,7 @@ package main
 import (
 	"github.com/SQL-Online-Judge/backend/internal/core/initialize"
 	"github.com/SQL-Online-Judge/backend/internal/core/restapi"
+	"github.com/SQL-Online-Judge/backend/internal/core/worker"
 	"github.com/SQL-Online-Judge/backend/internal/pkg/db/mongo"
 	"github.com/SQL-Online-Judge/backend/internal/pkg/db/redis"
 	"github.com/SQL-Online-Judge/backend/internal/pkg/logger"
@@ -13,6 +14,7 @@ func main() {
 	defer redis.GetRedis().Close()
 
 	initialize.Initialize()
+	worker.Start()
 	logger.Logger.Info("Hello, SQL-Online-Judge!")
 
 	restapi.Serve()
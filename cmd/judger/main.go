@@ -1,9 +1,20 @@
 package main
 
 import (
+	"github.com/SQL-Online-Judge/backend/internal/judger"
+	_ "github.com/SQL-Online-Judge/backend/internal/judger/engine/all"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/db/redis"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/heartbeat"
 	"github.com/SQL-Online-Judge/backend/internal/pkg/logger"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/mq"
 )
 
 func main() {
-	logger.Logger.Info("Hello, World!")
+	defer redis.GetRedis().Close()
+
+	j := judger.NewJudger(mq.NewService(mq.NewRedisMQ(redis.GetRedisDB())), heartbeat.NewRegistry(redis.GetRedisDB()))
+	defer j.Close()
+	logger.Logger.Info("Hello, SQL-Online-Judge Judger!")
+
+	j.Serve()
 }
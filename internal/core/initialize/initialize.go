@@ -22,12 +22,15 @@ func Initialize() {
 		logger.Logger.Info("MongoDB has been initialized")
 	}
 
+	// creating an index that exists is a no-op, and this creates those added
+	// since the database was initialized
+	createIndex()
+
 	initRedis()
 }
 
 func initMongo() {
 	logger.Logger.Info("initializing MongoDB...")
-	createIndex()
 	createAdmin()
 	logger.Logger.Info("successfully initialized MongoDB")
 }
@@ -39,11 +42,20 @@ func createIndex() {
 			{"field": "username", "unique": "true"},
 			{"field": "deleted", "unique": "false"},
 		},
-		"class":      {{"field": "classID", "unique": "true"}},
-		"problem":    {{"field": "problemID", "unique": "true"}},
-		"answer":     {{"field": "answerID", "unique": "true"}},
-		"task":       {{"field": "taskID", "unique": "true"}},
-		"submission": {{"field": "submissionID", "unique": "true"}},
+		"class":   {{"field": "classID", "unique": "true"}},
+		"problem": {{"field": "problemID", "unique": "true"}},
+		"answer":  {{"field": "answerID", "unique": "true"}},
+		"task":    {{"field": "taskID", "unique": "true"}},
+		"submission": {
+			{"field": "submissionID", "unique": "true"},
+			{"field": "outbox.nextAttemptTime", "unique": "false"},
+			{"field": "rejudgeID", "unique": "false"},
+		},
+		"rejudge": {{"field": "rejudgeID", "unique": "true"}},
+		"similarity": {
+			{"field": "reportID", "unique": "true"},
+			{"field": "taskID", "unique": "false"},
+		},
 		"message":    {{"field": "messageID", "unique": "true"}},
 		"messageBox": {{"field": "userID", "unique": "true"}},
 	}
@@ -93,7 +105,7 @@ func initRedis() {
 }
 
 func initRedisMQ() {
-	queues := []string{"answer_generate", "answer_output", "submission", "judge_result"}
+	queues := []string{"answer_generate", "answer_output", "submission", "judge_result", "run", "similarity"}
 
 	for _, queue := range queues {
 		exists, err := service.MQService.IsQueueExists(queue)
@@ -0,0 +1,124 @@
+// Package outbox relays the outbox entries of submissions to the submission
+// queue.
+package outbox
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SQL-Online-Judge/backend/internal/core/repository"
+	"github.com/SQL-Online-Judge/backend/internal/model"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// ErrCannotJudge is returned by publish for a submission that will never be
+// judged, e.g. its answer does not exist. The relay finishes it as System
+// Error instead of retrying.
+var ErrCannotJudge = fmt.Errorf("submission cannot be judged")
+
+const (
+	// lease is how long a claimed outbox entry is hidden from other relays
+	// while it is being published
+	lease = 30 * time.Second
+	// maxBackoff caps the delay between two publish attempts
+	maxBackoff = time.Minute
+)
+
+// Store holds the outbox entries, see repository.SubmissionRepository.
+type Store interface {
+	ClaimSubmissionOutbox(lease time.Duration) (*model.Submission, error)
+	CompleteSubmissionOutbox(submissionID int64) (*model.Submission, error)
+	RetrySubmissionOutbox(submissionID int64, nextAttemptTime time.Time, lastError string) error
+	FailSubmissionOutbox(submissionID int64, output string) (*model.Submission, error)
+}
+
+type Relay struct {
+	store Store
+	// publish publishes the judge request of a submission
+	publish func(submission *model.Submission) error
+	// notify is called with every submission the relay marks Queued or
+	// finishes
+	notify func(submission *model.Submission)
+}
+
+func NewRelay(store Store, publish func(submission *model.Submission) error, notify func(submission *model.Submission)) *Relay {
+	return &Relay{
+		store:   store,
+		publish: publish,
+		notify:  notify,
+	}
+}
+
+// Run publishes every due outbox entry. An entry that fails to publish is
+// retried later with backoff, unless it cannot be judged at all.
+func (r *Relay) Run() error {
+	for {
+		submission, err := r.store.ClaimSubmissionOutbox(lease)
+		if errors.Is(err, repository.ErrNoOutboxEntryDue) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to claim submission outbox: %w", err)
+		}
+
+		err = r.publish(submission)
+		if errors.Is(err, ErrCannotJudge) {
+			r.fail(submission, err)
+			continue
+		}
+		if err != nil {
+			logger.Logger.Warn("failed to publish submission, retry later", zap.Int64("submissionID", submission.SubmissionID), zap.Int32("attempts", submission.Outbox.Attempts), zap.Error(err))
+			nextAttemptTime := time.Now().Add(backoff(submission.Outbox.Attempts))
+			err = r.store.RetrySubmissionOutbox(submission.SubmissionID, nextAttemptTime, err.Error())
+			if err != nil {
+				// the entry is retried after the lease instead
+				logger.Logger.Error("failed to retry submission outbox", zap.Int64("submissionID", submission.SubmissionID), zap.Error(err))
+			}
+			continue
+		}
+
+		queued, err := r.store.CompleteSubmissionOutbox(submission.SubmissionID)
+		if errors.Is(err, repository.ErrSubmissionNotUpdated) {
+			continue
+		}
+		if err != nil {
+			// the entry is published again after the lease, which the judger
+			// tolerates
+			logger.Logger.Error("failed to complete submission outbox", zap.Int64("submissionID", submission.SubmissionID), zap.Error(err))
+			continue
+		}
+		if queued.JudgeStatus == model.JudgeStatusQueued {
+			r.notify(queued)
+		}
+	}
+}
+
+// fail finishes a submission that cannot be judged because of err.
+func (r *Relay) fail(submission *model.Submission, err error) {
+	logger.Logger.Warn("submission cannot be judged", zap.Int64("submissionID", submission.SubmissionID), zap.Error(err))
+	failed, err := r.store.FailSubmissionOutbox(submission.SubmissionID, err.Error())
+	if errors.Is(err, repository.ErrSubmissionNotUpdated) {
+		return
+	}
+	if err != nil {
+		// the entry is tried again after the lease
+		logger.Logger.Error("failed to fail submission outbox", zap.Int64("submissionID", submission.SubmissionID), zap.Error(err))
+		return
+	}
+	r.notify(failed)
+}
+
+// backoff returns the delay before the next publish attempt of an entry
+// attempted attempts times: a second, doubled with each attempt.
+func backoff(attempts int32) time.Duration {
+	if attempts > 6 {
+		return maxBackoff
+	}
+	backoff := time.Second << attempts
+	if backoff > maxBackoff {
+		return maxBackoff
+	}
+	return backoff
+}
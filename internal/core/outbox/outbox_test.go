@@ -0,0 +1,222 @@
+package outbox
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/SQL-Online-Judge/backend/internal/core/repository"
+	"github.com/SQL-Online-Judge/backend/internal/model"
+)
+
+type retry struct {
+	submissionID    int64
+	nextAttemptTime time.Time
+	lastError       string
+}
+
+// fakeStore hands out its entries in order and records what the relay does
+// with them.
+type fakeStore struct {
+	entries   []*model.Submission
+	claimErr  error
+	retryErr  error
+	completed []int64
+	retries   []retry
+	failed    []int64
+	// status is the status of the submissions once completed, Queued if
+	// empty; a submission of notUpdated is not updated at all
+	status     map[int64]string
+	notUpdated map[int64]bool
+}
+
+func (f *fakeStore) ClaimSubmissionOutbox(lease time.Duration) (*model.Submission, error) {
+	if f.claimErr != nil {
+		return nil, f.claimErr
+	}
+	if len(f.entries) == 0 {
+		return nil, fmt.Errorf("%w", repository.ErrNoOutboxEntryDue)
+	}
+	submission := f.entries[0]
+	f.entries = f.entries[1:]
+	submission.Outbox.Attempts++
+	return submission, nil
+}
+
+func (f *fakeStore) CompleteSubmissionOutbox(submissionID int64) (*model.Submission, error) {
+	if f.notUpdated[submissionID] {
+		return nil, fmt.Errorf("%w", repository.ErrSubmissionNotUpdated)
+	}
+	f.completed = append(f.completed, submissionID)
+
+	status := f.status[submissionID]
+	if status == "" {
+		status = model.JudgeStatusQueued
+	}
+	return &model.Submission{SubmissionID: submissionID, JudgeStatus: status}, nil
+}
+
+func (f *fakeStore) RetrySubmissionOutbox(submissionID int64, nextAttemptTime time.Time, lastError string) error {
+	f.retries = append(f.retries, retry{submissionID, nextAttemptTime, lastError})
+	return f.retryErr
+}
+
+func (f *fakeStore) FailSubmissionOutbox(submissionID int64, output string) (*model.Submission, error) {
+	f.failed = append(f.failed, submissionID)
+	return &model.Submission{SubmissionID: submissionID, JudgeStatus: model.JudgeStatusSystemError, JudgerOutput: output}, nil
+}
+
+func newEntry(submissionID int64, attempts int32) *model.Submission {
+	return &model.Submission{
+		SubmissionID: submissionID,
+		JudgeStatus:  model.JudgeStatusPending,
+		Outbox:       &model.SubmissionOutbox{Attempts: attempts},
+	}
+}
+
+func TestRelayRun(t *testing.T) {
+	errPublish := errors.New("queue is down")
+
+	tests := []struct {
+		name string
+		// store is filled in by the test
+		store *fakeStore
+		// failing submissions fail to publish, unjudgeable ones for good
+		failing       map[int64]bool
+		unjudgeable   map[int64]bool
+		wantPublished []int64
+		wantCompleted []int64
+		wantNotified  []int64
+		wantRetried   []int64
+		wantFailed    []int64
+	}{
+		{
+			name:          "publishes every entry",
+			store:         &fakeStore{entries: []*model.Submission{newEntry(1, 0), newEntry(2, 0)}},
+			wantPublished: []int64{1, 2},
+			wantCompleted: []int64{1, 2},
+			wantNotified:  []int64{1, 2},
+		},
+		{
+			name:          "retries failed entries",
+			store:         &fakeStore{entries: []*model.Submission{newEntry(1, 0), newEntry(2, 3)}},
+			failing:       map[int64]bool{2: true},
+			wantPublished: []int64{1, 2},
+			wantCompleted: []int64{1},
+			wantNotified:  []int64{1},
+			wantRetried:   []int64{2},
+		},
+		{
+			name:          "goes on when the retry cannot be stored",
+			store:         &fakeStore{entries: []*model.Submission{newEntry(1, 0), newEntry(2, 0)}, retryErr: errors.New("mongo is down")},
+			failing:       map[int64]bool{1: true},
+			wantPublished: []int64{1, 2},
+			wantCompleted: []int64{2},
+			wantNotified:  []int64{2},
+			wantRetried:   []int64{1},
+		},
+		{
+			name:          "finishes entries that cannot be judged",
+			store:         &fakeStore{entries: []*model.Submission{newEntry(1, 0), newEntry(2, 0)}},
+			unjudgeable:   map[int64]bool{1: true},
+			wantPublished: []int64{1, 2},
+			wantCompleted: []int64{2},
+			wantNotified:  []int64{1, 2},
+			wantFailed:    []int64{1},
+		},
+		{
+			name: "announces only submissions marked queued",
+			store: &fakeStore{
+				entries:    []*model.Submission{newEntry(1, 0), newEntry(2, 0), newEntry(3, 0)},
+				status:     map[int64]string{2: model.JudgeStatusAccepted},
+				notUpdated: map[int64]bool{3: true},
+			},
+			wantPublished: []int64{1, 2, 3},
+			wantCompleted: []int64{1, 2},
+			wantNotified:  []int64{1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var published, notified []int64
+			publish := func(submission *model.Submission) error {
+				published = append(published, submission.SubmissionID)
+				if tt.unjudgeable[submission.SubmissionID] {
+					return fmt.Errorf("%w: answer not found", ErrCannotJudge)
+				}
+				if tt.failing[submission.SubmissionID] {
+					return errPublish
+				}
+				return nil
+			}
+			notify := func(submission *model.Submission) {
+				notified = append(notified, submission.SubmissionID)
+			}
+
+			start := time.Now()
+			err := NewRelay(tt.store, publish, notify).Run()
+			if err != nil {
+				t.Fatalf("Run() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(published, tt.wantPublished) {
+				t.Errorf("published %v, want %v", published, tt.wantPublished)
+			}
+			if !reflect.DeepEqual(tt.store.completed, tt.wantCompleted) {
+				t.Errorf("completed %v, want %v", tt.store.completed, tt.wantCompleted)
+			}
+			if !reflect.DeepEqual(notified, tt.wantNotified) {
+				t.Errorf("announced %v, want %v", notified, tt.wantNotified)
+			}
+			if !reflect.DeepEqual(tt.store.failed, tt.wantFailed) {
+				t.Errorf("failed %v, want %v", tt.store.failed, tt.wantFailed)
+			}
+
+			var retried []int64
+			for _, r := range tt.store.retries {
+				retried = append(retried, r.submissionID)
+				if r.lastError != errPublish.Error() {
+					t.Errorf("last error of %d = %q, want %q", r.submissionID, r.lastError, errPublish.Error())
+				}
+				if r.nextAttemptTime.Before(start.Add(time.Second)) {
+					t.Errorf("next attempt of %d at %v, want a backoff of at least a second", r.submissionID, r.nextAttemptTime.Sub(start))
+				}
+			}
+			if !reflect.DeepEqual(retried, tt.wantRetried) {
+				t.Errorf("retried %v, want %v", retried, tt.wantRetried)
+			}
+		})
+	}
+}
+
+func TestRelayRunClaimError(t *testing.T) {
+	errClaim := errors.New("mongo is down")
+	store := &fakeStore{claimErr: errClaim}
+
+	err := NewRelay(store, nil, nil).Run()
+	if !errors.Is(err, errClaim) {
+		t.Errorf("Run() error = %v, want %v", err, errClaim)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int32
+		want     time.Duration
+	}{
+		{0, time.Second},
+		{1, 2 * time.Second},
+		{5, 32 * time.Second},
+		{6, time.Minute},
+		{7, time.Minute},
+		{1000, time.Minute},
+	}
+	for _, tt := range tests {
+		if got := backoff(tt.attempts); got != tt.want {
+			t.Errorf("backoff(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
@@ -2,12 +2,14 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"time"
 
 	"github.com/SQL-Online-Judge/backend/internal/model"
 	"github.com/SQL-Online-Judge/backend/internal/pkg/logger"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/mq"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
@@ -15,7 +17,21 @@ import (
 	"go.uber.org/zap"
 )
 
-var ErrUserIsNil = fmt.Errorf("user is nil")
+var (
+	ErrUserIsNil            = fmt.Errorf("user is nil")
+	ErrNoOutboxEntryDue     = fmt.Errorf("no outbox entry is due")
+	ErrSubmissionNotUpdated = fmt.Errorf("submission is not updated")
+	ErrAnswerNotFound       = fmt.Errorf("answer not found")
+)
+
+// submissionStatusProjection leaves out the bulky fields of a submission,
+// for when only its status is needed.
+var submissionStatusProjection = bson.D{
+	{Key: "submittedSQL", Value: 0},
+	{Key: "judgerOutput", Value: 0},
+	{Key: "datasetResults", Value: 0},
+	{Key: "outbox", Value: 0},
+}
 
 type MongoRepository struct {
 	db *mongo.Database
@@ -51,6 +67,14 @@ func (mr *MongoRepository) getSubmissionCollection() *mongo.Collection {
 	return mr.db.Collection("submission")
 }
 
+func (mr *MongoRepository) getRejudgeCollection() *mongo.Collection {
+	return mr.db.Collection("rejudge")
+}
+
+func (mr *MongoRepository) getSimilarityCollection() *mongo.Collection {
+	return mr.db.Collection("similarity")
+}
+
 func (mr *MongoRepository) ExistByUserID(userID int64) bool {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -678,8 +702,12 @@ func (mr *MongoRepository) UpdateAnswer(answer *model.Answer) error {
 		{Key: "prepareSQL", Value: answer.PrepareSQL},
 		{Key: "answerSQL", Value: answer.AnswerSQL},
 		{Key: "judgeSQL", Value: answer.JudgeSQL},
+		{Key: "compareOptions", Value: answer.CompareOptions},
+		{Key: "datasets", Value: answer.Datasets},
+		{Key: "answerOutput", Value: ""},
 		{Key: "isReady", Value: false},
-	}}}
+		{Key: "generateError", Value: ""},
+	}}, {Key: "$inc", Value: bson.D{{Key: "revision", Value: 1}}}}
 	_, err := mr.getAnswerCollection().UpdateOne(ctx, filter, update)
 	if err != nil {
 		logger.Logger.Error("failed to update answer", zap.Int64("answerID", answer.AnswerID), zap.Error(err))
@@ -718,6 +746,99 @@ func (mr *MongoRepository) FindAnswersByProblemID(problemID int64) ([]*model.Ans
 	return answers, nil
 }
 
+func (mr *MongoRepository) FindByAnswerID(answerID int64) (*model.Answer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.D{
+		{Key: "answerID", Value: answerID},
+		{Key: "deleted", Value: false},
+	}
+	var answer model.Answer
+	err := mr.getAnswerCollection().FindOne(ctx, filter).Decode(&answer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find answer by answer id: %w", err)
+	}
+
+	return &answer, nil
+}
+
+func (mr *MongoRepository) FindUnreadyAnswers() ([]*model.Answer, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.D{
+		{Key: "isReady", Value: false},
+		{Key: "generateError", Value: bson.D{{Key: "$in", Value: bson.A{"", nil}}}},
+		{Key: "deleted", Value: false},
+	}
+	cursor, err := mr.getAnswerCollection().Find(ctx, filter)
+	if err != nil {
+		logger.Logger.Error("failed to get unready answers", zap.Error(err))
+		return nil, fmt.Errorf("failed to get unready answers: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var answers []*model.Answer
+	err = cursor.All(ctx, &answers)
+	if err != nil {
+		logger.Logger.Error("failed to decode answers", zap.Error(err))
+		return nil, fmt.Errorf("failed to decode answers: %w", err)
+	}
+
+	return answers, nil
+}
+
+// UpdateAnswerOutput stores the outputs of the answer on its datasets; the
+// first one is also the output of the answer itself.
+func (mr *MongoRepository) UpdateAnswerOutput(answerID, revision int64, answerOutputs []string, generateError string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// an output generated for an older revision of the answer is discarded
+	filter := bson.D{
+		{Key: "answerID", Value: answerID},
+		{Key: "revision", Value: revision},
+	}
+	if revision == 0 {
+		// answers created before revisions were introduced have no revision field
+		filter[1] = bson.E{Key: "revision", Value: bson.D{{Key: "$in", Value: bson.A{0, nil}}}}
+	}
+
+	answerOutput := ""
+	if len(answerOutputs) > 0 {
+		answerOutput = answerOutputs[0]
+	} else {
+		answerOutputs = []string{}
+	}
+	update := mongo.Pipeline{{{Key: "$set", Value: bson.D{
+		{Key: "answerOutput", Value: answerOutput},
+		{Key: "isReady", Value: generateError == ""},
+		{Key: "generateError", Value: generateError},
+		{Key: "datasets", Value: bson.D{{Key: "$map", Value: bson.D{
+			{Key: "input", Value: bson.D{{Key: "$range", Value: bson.A{
+				0,
+				bson.D{{Key: "$size", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$datasets", bson.A{}}}}}},
+			}}}},
+			{Key: "as", Value: "i"},
+			{Key: "in", Value: bson.D{{Key: "$mergeObjects", Value: bson.A{
+				bson.D{{Key: "$arrayElemAt", Value: bson.A{"$datasets", "$$i"}}},
+				bson.D{{Key: "answerOutput", Value: bson.D{{Key: "$arrayElemAt", Value: bson.A{
+					bson.D{{Key: "$literal", Value: answerOutputs}},
+					"$$i",
+				}}}}},
+			}}}},
+		}}}},
+	}}}}
+	_, err := mr.getAnswerCollection().UpdateOne(ctx, filter, update)
+	if err != nil {
+		logger.Logger.Error("failed to update answer output", zap.Int64("answerID", answerID), zap.Error(err))
+		return fmt.Errorf("failed to update answer output: %w", err)
+	}
+
+	return nil
+}
+
 func (mr *MongoRepository) CreateTask(task *model.Task) (int64, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
@@ -1218,6 +1339,31 @@ func (mr *MongoRepository) FindSubmissionsByStudentID(studentID int64) ([]*model
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
+	// submissions judged before datasets existed have no score
+	scoreShare := bson.D{{Key: "$ifNull", Value: bson.A{
+		"$score",
+		bson.D{{Key: "$cond", Value: bson.A{
+			bson.D{{Key: "$eq", Value: bson.A{"$judgeStatus", model.JudgeStatusAccepted}}},
+			1,
+			0,
+		}}},
+	}}}
+	// the score the task gives to the problem of the submission
+	taskProblems := bson.D{{Key: "$filter", Value: bson.D{
+		{Key: "input", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$task.problems", bson.A{}}}}},
+		{Key: "as", Value: "p"},
+		{Key: "cond", Value: bson.D{{Key: "$eq", Value: bson.A{"$$p.problemID", "$problemID"}}}},
+	}}}
+	taskProblemScores := bson.D{{Key: "$map", Value: bson.D{
+		{Key: "input", Value: taskProblems},
+		{Key: "as", Value: "p"},
+		{Key: "in", Value: "$$p.score"},
+	}}}
+	problemScore := bson.D{{Key: "$ifNull", Value: bson.A{
+		bson.D{{Key: "$arrayElemAt", Value: bson.A{taskProblemScores, 0}}},
+		0,
+	}}}
+
 	pipeline := mongo.Pipeline{
 		{{Key: "$match", Value: bson.D{{Key: "submitterID", Value: studentID}}}},
 		{{Key: "$lookup", Value: bson.D{
@@ -1245,6 +1391,7 @@ func (mr *MongoRepository) FindSubmissionsByStudentID(studentID int64) ([]*model
 			{Key: "dbName", Value: "$dbName"},
 			{Key: "judgeStatus", Value: "$judgeStatus"},
 			{Key: "timeCost", Value: "$timeCost"},
+			{Key: "score", Value: bson.D{{Key: "$multiply", Value: bson.A{scoreShare, problemScore}}}},
 		}}},
 	}
 
@@ -1282,23 +1429,27 @@ func (mr *MongoRepository) IsStudentSubmission(studentID, submissionID int64) bo
 	return count > 0
 }
 
-func (mr *MongoRepository) GetSubmittedSQL(submissionID int64) (string, error) {
+func (mr *MongoRepository) GetSubmittedSQL(submissionID int64) (*model.SubmitedSQL, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
 
 	filter := bson.D{{Key: "submissionID", Value: submissionID}}
 	option := options.FindOne().SetProjection(bson.D{
 		{Key: "submissionID", Value: 1},
+		{Key: "problemID", Value: 1},
 		{Key: "submittedSQL", Value: 1},
+		{Key: "judgeStatus", Value: 1},
+		{Key: "judgerOutput", Value: 1},
+		{Key: "datasetResults", Value: 1},
 	})
 	var submission model.SubmitedSQL
 	err := mr.getSubmissionCollection().FindOne(ctx, filter, option).Decode(&submission)
 	if err != nil {
 		logger.Logger.Error("failed to find submission by submissionID", zap.Int64("submissionID", submissionID), zap.Error(err))
-		return "", fmt.Errorf("failed to find submission by submissionID: %w", err)
+		return nil, fmt.Errorf("failed to find submission by submissionID: %w", err)
 	}
 
-	return submission.SubmittedSQL, nil
+	return &submission, nil
 }
 
 func (mr *MongoRepository) GetJudgeRequest(s *model.Submission) (*model.JudgeRequest, error) {
@@ -1308,6 +1459,7 @@ func (mr *MongoRepository) GetJudgeRequest(s *model.Submission) (*model.JudgeReq
 	judgeSubmission := model.JudgeSubmission{
 		SubmissionID: strconv.FormatInt(s.SubmissionID, 10),
 		SubmittedSQL: s.SubmittedSQL,
+		RejudgeID:    s.RejudgeID,
 	}
 
 	filter := bson.D{{Key: "problemID", Value: s.ProblemID}}
@@ -1315,6 +1467,11 @@ func (mr *MongoRepository) GetJudgeRequest(s *model.Submission) (*model.JudgeReq
 		Projection: bson.D{
 			{Key: "timeLimit", Value: 1},
 			{Key: "memoryLimit", Value: 1},
+			{Key: "outputRowLimit", Value: 1},
+			{Key: "outputSizeLimit", Value: 1},
+			{Key: "sqlPolicy", Value: 1},
+			{Key: "requiredConstructs", Value: 1},
+			{Key: "forbiddenConstructs", Value: 1},
 		},
 	}
 	var judgeProblem model.JudgeProblem
@@ -1333,6 +1490,9 @@ func (mr *MongoRepository) GetJudgeRequest(s *model.Submission) (*model.JudgeReq
 
 	var judgeAnswer model.JudgeAnswer
 	err = mr.getAnswerCollection().FindOne(ctx, filter).Decode(&judgeAnswer)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("%w: problem %d has no %s answer", ErrAnswerNotFound, problemID, dbName)
+	}
 	if err != nil {
 		logger.Logger.Error("failed to find answer by problemID and dbName", zap.Int64("problemID", problemID), zap.String("dbName", dbName), zap.Error(err))
 		return nil, fmt.Errorf("failed to find answer by problemID and dbName: %w", err)
@@ -1361,3 +1521,465 @@ func (mr *MongoRepository) UpdateSubmissionStatus(submissionID int64, status str
 
 	return nil
 }
+
+// submissionResultFilter matches the submission a result of the judge
+// request made for rejudgeID may be written to: the submission must not
+// have been rejudged since, and a finished submission is never moved back
+// to an intermediate state.
+func submissionResultFilter(submissionID, rejudgeID int64, result *model.JudgeResult) bson.D {
+	filter := bson.D{{Key: "submissionID", Value: submissionID}}
+	if rejudgeID == 0 {
+		filter = append(filter, bson.E{Key: "rejudgeID", Value: bson.D{{Key: "$exists", Value: false}}})
+	} else {
+		filter = append(filter, bson.E{Key: "rejudgeID", Value: rejudgeID})
+	}
+
+	if !result.IsFinal() {
+		filter = append(filter, bson.E{Key: "judgeStatus", Value: bson.D{{Key: "$in", Value: []string{
+			model.JudgeStatusPending,
+			model.JudgeStatusQueued,
+			model.JudgeStatusJudging,
+		}}}})
+	}
+	return filter
+}
+
+// UpdateSubmissionResult writes the result of the judge request made for
+// rejudgeID. It returns the submission updated, without its SQL, output and
+// dataset results, or ErrSubmissionNotUpdated if the result is stale, see
+// submissionResultFilter.
+func (mr *MongoRepository) UpdateSubmissionResult(submissionID, rejudgeID int64, result *model.JudgeResult) (*model.Submission, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := submissionResultFilter(submissionID, rejudgeID, result)
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "judgeStatus", Value: result.JudgeStatus},
+		{Key: "timeCost", Value: result.TimeCost},
+		{Key: "judgerOutput", Value: result.JudgerOutput},
+		{Key: "score", Value: result.Score},
+		{Key: "datasetResults", Value: result.Datasets},
+	}}}
+	option := options.FindOneAndUpdate().
+		SetProjection(submissionStatusProjection).
+		SetReturnDocument(options.After)
+
+	var submission model.Submission
+	err := mr.getSubmissionCollection().FindOneAndUpdate(ctx, filter, update, option).Decode(&submission)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("%w", ErrSubmissionNotUpdated)
+	}
+	if err != nil {
+		logger.Logger.Error("failed to update submission result", zap.Int64("submissionID", submissionID), zap.Error(err))
+		return nil, fmt.Errorf("failed to update submission result: %w", err)
+	}
+
+	return &submission, nil
+}
+
+// SweepPendingSubmissions gives an outbox entry to every pending submission
+// that has none, e.g. one created before the outbox existed, so the relay
+// publishes it.
+func (mr *MongoRepository) SweepPendingSubmissions() (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.D{
+		{Key: "judgeStatus", Value: model.JudgeStatusPending},
+		{Key: "outbox", Value: bson.D{{Key: "$exists", Value: false}}},
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "outbox", Value: &model.SubmissionOutbox{
+		NextAttemptTime: time.Now(),
+	}}}}}
+	result, err := mr.getSubmissionCollection().UpdateMany(ctx, filter, update)
+	if err != nil {
+		logger.Logger.Error("failed to sweep pending submissions", zap.Error(err))
+		return 0, fmt.Errorf("failed to sweep pending submissions: %w", err)
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// ClaimSubmissionOutbox returns a submission whose outbox entry is due and
+// postpones the entry by lease, so no other relay publishes it meanwhile.
+func (mr *MongoRepository) ClaimSubmissionOutbox(lease time.Duration) (*model.Submission, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	filter := bson.D{{Key: "outbox.nextAttemptTime", Value: bson.D{{Key: "$lte", Value: now}}}}
+	update := bson.D{
+		{Key: "$set", Value: bson.D{{Key: "outbox.nextAttemptTime", Value: now.Add(lease)}}},
+		{Key: "$inc", Value: bson.D{{Key: "outbox.attempts", Value: 1}}},
+	}
+	option := options.FindOneAndUpdate().
+		SetSort(bson.D{{Key: "outbox.nextAttemptTime", Value: 1}}).
+		SetReturnDocument(options.After)
+
+	var submission model.Submission
+	err := mr.getSubmissionCollection().FindOneAndUpdate(ctx, filter, update, option).Decode(&submission)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("%w", ErrNoOutboxEntryDue)
+	}
+	if err != nil {
+		logger.Logger.Error("failed to claim submission outbox", zap.Error(err))
+		return nil, fmt.Errorf("failed to claim submission outbox: %w", err)
+	}
+
+	return &submission, nil
+}
+
+// CompleteSubmissionOutbox removes the outbox entry of a published
+// submission and marks it Queued unless the judger has already moved it on.
+// It returns the submission updated, without its SQL, output and dataset
+// results.
+func (mr *MongoRepository) CompleteSubmissionOutbox(submissionID int64) (*model.Submission, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.D{{Key: "submissionID", Value: submissionID}}
+	update := mongo.Pipeline{
+		{{Key: "$unset", Value: "outbox"}},
+		{{Key: "$set", Value: bson.D{{Key: "judgeStatus", Value: bson.D{{Key: "$cond", Value: bson.A{
+			bson.D{{Key: "$eq", Value: bson.A{"$judgeStatus", model.JudgeStatusPending}}},
+			model.JudgeStatusQueued,
+			"$judgeStatus",
+		}}}}}}},
+	}
+	option := options.FindOneAndUpdate().
+		SetProjection(submissionStatusProjection).
+		SetReturnDocument(options.After)
+
+	var submission model.Submission
+	err := mr.getSubmissionCollection().FindOneAndUpdate(ctx, filter, update, option).Decode(&submission)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("%w", ErrSubmissionNotUpdated)
+	}
+	if err != nil {
+		logger.Logger.Error("failed to complete submission outbox", zap.Int64("submissionID", submissionID), zap.Error(err))
+		return nil, fmt.Errorf("failed to complete submission outbox: %w", err)
+	}
+
+	return &submission, nil
+}
+
+// FailSubmissionOutbox removes the outbox entry of a submission that cannot
+// be judged and finishes it as System Error with output. It returns the
+// submission updated, without its SQL, output and dataset results.
+func (mr *MongoRepository) FailSubmissionOutbox(submissionID int64, output string) (*model.Submission, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.D{
+		{Key: "submissionID", Value: submissionID},
+		{Key: "outbox", Value: bson.D{{Key: "$exists", Value: true}}},
+	}
+	update := bson.D{
+		{Key: "$unset", Value: bson.D{{Key: "outbox", Value: ""}}},
+		{Key: "$set", Value: bson.D{
+			{Key: "judgeStatus", Value: model.JudgeStatusSystemError},
+			{Key: "judgerOutput", Value: output},
+			{Key: "score", Value: 0},
+			{Key: "datasetResults", Value: nil},
+		}},
+	}
+	option := options.FindOneAndUpdate().
+		SetProjection(submissionStatusProjection).
+		SetReturnDocument(options.After)
+
+	var submission model.Submission
+	err := mr.getSubmissionCollection().FindOneAndUpdate(ctx, filter, update, option).Decode(&submission)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, fmt.Errorf("%w", ErrSubmissionNotUpdated)
+	}
+	if err != nil {
+		logger.Logger.Error("failed to fail submission outbox", zap.Int64("submissionID", submissionID), zap.Error(err))
+		return nil, fmt.Errorf("failed to fail submission outbox: %w", err)
+	}
+
+	return &submission, nil
+}
+
+func (mr *MongoRepository) RetrySubmissionOutbox(submissionID int64, nextAttemptTime time.Time, lastError string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.D{
+		{Key: "submissionID", Value: submissionID},
+		{Key: "outbox", Value: bson.D{{Key: "$exists", Value: true}}},
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "outbox.nextAttemptTime", Value: nextAttemptTime},
+		{Key: "outbox.lastError", Value: lastError},
+	}}}
+	_, err := mr.getSubmissionCollection().UpdateOne(ctx, filter, update)
+	if err != nil {
+		logger.Logger.Error("failed to retry submission outbox", zap.Int64("submissionID", submissionID), zap.Error(err))
+		return fmt.Errorf("failed to retry submission outbox: %w", err)
+	}
+
+	return nil
+}
+
+func (mr *MongoRepository) FindSubmissionByID(submissionID int64) (*model.Submission, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.D{{Key: "submissionID", Value: submissionID}}
+	var submission model.Submission
+	err := mr.getSubmissionCollection().FindOne(ctx, filter).Decode(&submission)
+	if err != nil {
+		logger.Logger.Error("failed to find submission by submissionID", zap.Int64("submissionID", submissionID), zap.Error(err))
+		return nil, fmt.Errorf("failed to find submission by submissionID: %w", err)
+	}
+
+	return &submission, nil
+}
+
+// rejudgeFilter matches the submissions in the scope of r that can be
+// rejudged: those being judged already are left alone, and so are those
+// rejected on submission, which were never judged.
+func rejudgeFilter(r *model.Rejudge) (bson.D, error) {
+	var filter bson.D
+	switch r.Scope {
+	case model.RejudgeScopeSubmission:
+		filter = bson.D{{Key: "submissionID", Value: r.TargetID}}
+	case model.RejudgeScopeProblem:
+		filter = bson.D{{Key: "problemID", Value: r.TargetID}}
+	case model.RejudgeScopeTask:
+		filter = bson.D{{Key: "taskID", Value: r.TargetID}}
+	default:
+		return nil, fmt.Errorf("invalid rejudge scope %q", r.Scope)
+	}
+	if r.DBName != "" {
+		filter = append(filter, bson.E{Key: "dbName", Value: r.DBName})
+	}
+
+	filter = append(filter,
+		bson.E{Key: "judgeStatus", Value: bson.D{{Key: "$nin", Value: []string{
+			model.JudgeStatusPending,
+			model.JudgeStatusQueued,
+			model.JudgeStatusJudging,
+		}}}},
+		// a submission rejected by the policy on submission has no dataset result
+		bson.E{Key: "$nor", Value: bson.A{bson.D{
+			{Key: "judgeStatus", Value: bson.D{{Key: "$in", Value: []string{
+				model.JudgeStatusPolicyViolation,
+			}}}},
+			{Key: "datasetResults.0", Value: bson.D{{Key: "$exists", Value: false}}},
+		}}},
+	)
+	return filter, nil
+}
+
+// RejudgeSubmissions resets the submissions in the scope of r that can be
+// rejudged to Pending and hands them to the outbox relay, which builds
+// fresh judge requests.
+func (mr *MongoRepository) RejudgeSubmissions(r *model.Rejudge) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	filter, err := rejudgeFilter(r)
+	if err != nil {
+		return 0, err
+	}
+
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "judgeStatus", Value: model.JudgeStatusPending},
+		{Key: "timeCost", Value: 0},
+		{Key: "judgerOutput", Value: ""},
+		{Key: "score", Value: 0},
+		{Key: "datasetResults", Value: nil},
+		{Key: "rejudgeID", Value: r.RejudgeID},
+		// a rejudge must not hold up the submissions of students
+		{Key: "outbox", Value: &model.SubmissionOutbox{NextAttemptTime: time.Now(), Priority: int(mq.PriorityLow)}},
+	}}}
+	result, err := mr.getSubmissionCollection().UpdateMany(ctx, filter, update)
+	if err != nil {
+		logger.Logger.Error("failed to rejudge submissions", zap.Int64("rejudgeID", r.RejudgeID), zap.Error(err))
+		return 0, fmt.Errorf("failed to rejudge submissions: %w", err)
+	}
+
+	return result.MatchedCount, nil
+}
+
+func (mr *MongoRepository) CreateRejudge(r *model.Rejudge) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := mr.getRejudgeCollection().InsertOne(ctx, r)
+	if err != nil {
+		logger.Logger.Error("failed to create rejudge", zap.Error(err))
+		return 0, fmt.Errorf("failed to create rejudge: %w", err)
+	}
+
+	return r.RejudgeID, nil
+}
+
+func (mr *MongoRepository) FindRejudgeByID(rejudgeID int64) (*model.Rejudge, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.D{{Key: "rejudgeID", Value: rejudgeID}}
+	var rejudge model.Rejudge
+	err := mr.getRejudgeCollection().FindOne(ctx, filter).Decode(&rejudge)
+	if err != nil {
+		logger.Logger.Error("failed to find rejudge by rejudgeID", zap.Int64("rejudgeID", rejudgeID), zap.Error(err))
+		return nil, fmt.Errorf("failed to find rejudge by rejudgeID: %w", err)
+	}
+
+	return &rejudge, nil
+}
+
+// CountUnfinishedRejudgeSubmissions counts the submissions of a rejudge that
+// have no verdict yet.
+func (mr *MongoRepository) CountUnfinishedRejudgeSubmissions(rejudgeID int64) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.D{
+		{Key: "rejudgeID", Value: rejudgeID},
+		{Key: "judgeStatus", Value: bson.D{{Key: "$in", Value: []string{
+			model.JudgeStatusPending,
+			model.JudgeStatusQueued,
+			model.JudgeStatusJudging,
+		}}}},
+	}
+	count, err := mr.getSubmissionCollection().CountDocuments(ctx, filter)
+	if err != nil {
+		logger.Logger.Error("failed to count unfinished rejudge submissions", zap.Int64("rejudgeID", rejudgeID), zap.Error(err))
+		return 0, fmt.Errorf("failed to count unfinished rejudge submissions: %w", err)
+	}
+
+	return count, nil
+}
+
+// FindLatestAcceptedSubmissions returns the last accepted submission of
+// every student to every problem of a task.
+func (mr *MongoRepository) FindLatestAcceptedSubmissions(taskID int64) ([]*model.SimilarSubmission, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.D{
+			{Key: "taskID", Value: taskID},
+			{Key: "judgeStatus", Value: model.JudgeStatusAccepted},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "submitTime", Value: -1}}}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{
+				{Key: "submitterID", Value: "$submitterID"},
+				{Key: "problemID", Value: "$problemID"},
+			}},
+			{Key: "submission", Value: bson.D{{Key: "$first", Value: "$$ROOT"}}},
+		}}},
+		{{Key: "$replaceWith", Value: "$submission"}},
+		{{Key: "$lookup", Value: bson.D{
+			{Key: "from", Value: "user"},
+			{Key: "localField", Value: "submitterID"},
+			{Key: "foreignField", Value: "userID"},
+			{Key: "as", Value: "user"},
+		}}},
+		{{Key: "$unwind", Value: "$user"}},
+		{{Key: "$project", Value: bson.D{
+			{Key: "_id", Value: 0},
+			{Key: "submissionID", Value: "$submissionID"},
+			{Key: "studentID", Value: "$submitterID"},
+			{Key: "username", Value: "$user.username"},
+			{Key: "problemID", Value: "$problemID"},
+			{Key: "dbName", Value: "$dbName"},
+			{Key: "submitTime", Value: "$submitTime"},
+			{Key: "submittedSQL", Value: "$submittedSQL"},
+		}}},
+		{{Key: "$sort", Value: bson.D{
+			{Key: "problemID", Value: 1},
+			{Key: "submitTime", Value: 1},
+		}}},
+	}
+
+	cursor, err := mr.getSubmissionCollection().Aggregate(ctx, pipeline)
+	if err != nil {
+		logger.Logger.Error("failed to aggregate", zap.Error(err))
+		return nil, fmt.Errorf("failed to aggregate: %w", err)
+	}
+	defer cursor.Close(ctx)
+
+	var submissions []*model.SimilarSubmission
+	err = cursor.All(ctx, &submissions)
+	if err != nil {
+		logger.Logger.Error("failed to decode submissions", zap.Error(err))
+		return nil, fmt.Errorf("failed to decode submissions: %w", err)
+	}
+
+	return submissions, nil
+}
+
+func (mr *MongoRepository) CreateSimilarityReport(r *model.SimilarityReport) (int64, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	_, err := mr.getSimilarityCollection().InsertOne(ctx, r)
+	if err != nil {
+		logger.Logger.Error("failed to create similarity report", zap.Error(err))
+		return 0, fmt.Errorf("failed to create similarity report: %w", err)
+	}
+
+	return r.ReportID, nil
+}
+
+// FinishSimilarityReport stores the outcome of a running report.
+func (mr *MongoRepository) FinishSimilarityReport(r *model.SimilarityReport) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.D{
+		{Key: "reportID", Value: r.ReportID},
+		{Key: "status", Value: model.SimilarityStatusRunning},
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "finishTime", Value: r.FinishTime},
+		{Key: "status", Value: r.Status},
+		{Key: "error", Value: r.Error},
+		{Key: "submissions", Value: r.Submissions},
+		{Key: "pairs", Value: r.Pairs},
+	}}}
+	_, err := mr.getSimilarityCollection().UpdateOne(ctx, filter, update)
+	if err != nil {
+		logger.Logger.Error("failed to finish similarity report", zap.Int64("reportID", r.ReportID), zap.Error(err))
+		return fmt.Errorf("failed to finish similarity report: %w", err)
+	}
+
+	return nil
+}
+
+func (mr *MongoRepository) FindSimilarityReportByID(reportID int64) (*model.SimilarityReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.D{{Key: "reportID", Value: reportID}}
+	var report model.SimilarityReport
+	err := mr.getSimilarityCollection().FindOne(ctx, filter).Decode(&report)
+	if err != nil {
+		logger.Logger.Error("failed to find similarity report by reportID", zap.Int64("reportID", reportID), zap.Error(err))
+		return nil, fmt.Errorf("failed to find similarity report by reportID: %w", err)
+	}
+
+	return &report, nil
+}
+
+// FindLatestSimilarityReport returns the last report created for a task.
+func (mr *MongoRepository) FindLatestSimilarityReport(taskID int64) (*model.SimilarityReport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	filter := bson.D{{Key: "taskID", Value: taskID}}
+	option := options.FindOne().SetSort(bson.D{{Key: "createTime", Value: -1}})
+	var report model.SimilarityReport
+	err := mr.getSimilarityCollection().FindOne(ctx, filter, option).Decode(&report)
+	if err != nil {
+		logger.Logger.Error("failed to find similarity report by taskID", zap.Int64("taskID", taskID), zap.Error(err))
+		return nil, fmt.Errorf("failed to find similarity report by taskID: %w", err)
+	}
+
+	return &report, nil
+}
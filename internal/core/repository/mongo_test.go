@@ -0,0 +1,111 @@
+package repository
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/SQL-Online-Judge/backend/internal/model"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// lookup returns the value of key in filter, and whether it is there.
+func lookup(filter bson.D, key string) (interface{}, bool) {
+	for _, e := range filter {
+		if e.Key == key {
+			return e.Value, true
+		}
+	}
+	return nil, false
+}
+
+func TestRejudgeFilter(t *testing.T) {
+	inFlight := bson.D{{Key: "$nin", Value: []string{
+		model.JudgeStatusPending,
+		model.JudgeStatusQueued,
+		model.JudgeStatusJudging,
+	}}}
+	rejected := bson.A{bson.D{
+		{Key: "judgeStatus", Value: bson.D{{Key: "$in", Value: []string{
+			model.JudgeStatusPolicyViolation,
+		}}}},
+		{Key: "datasetResults.0", Value: bson.D{{Key: "$exists", Value: false}}},
+	}}
+
+	tests := []struct {
+		name    string
+		rejudge *model.Rejudge
+		key     string
+		dbName  string
+	}{
+		{name: "submission", rejudge: &model.Rejudge{Scope: model.RejudgeScopeSubmission, TargetID: 1}, key: "submissionID"},
+		{name: "problem", rejudge: &model.Rejudge{Scope: model.RejudgeScopeProblem, TargetID: 1}, key: "problemID"},
+		{name: "problem in a dialect", rejudge: &model.Rejudge{Scope: model.RejudgeScopeProblem, TargetID: 1, DBName: "mysql"}, key: "problemID", dbName: "mysql"},
+		{name: "task", rejudge: &model.Rejudge{Scope: model.RejudgeScopeTask, TargetID: 1}, key: "taskID"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := rejudgeFilter(tt.rejudge)
+			if err != nil {
+				t.Fatalf("rejudgeFilter() error = %v", err)
+			}
+
+			if v, ok := lookup(filter, tt.key); !ok || v != tt.rejudge.TargetID {
+				t.Errorf("%s = %v, want %d", tt.key, v, tt.rejudge.TargetID)
+			}
+			v, ok := lookup(filter, "dbName")
+			if tt.dbName == "" && ok || tt.dbName != "" && v != tt.dbName {
+				t.Errorf("dbName = %v, want %q", v, tt.dbName)
+			}
+			if v, _ := lookup(filter, "judgeStatus"); !reflect.DeepEqual(v, inFlight) {
+				t.Errorf("judgeStatus = %v, want submissions in flight excluded", v)
+			}
+			if v, _ := lookup(filter, "$nor"); !reflect.DeepEqual(v, rejected) {
+				t.Errorf("$nor = %v, want rejected submissions excluded", v)
+			}
+		})
+	}
+
+	_, err := rejudgeFilter(&model.Rejudge{Scope: "everything"})
+	if err == nil {
+		t.Errorf("rejudgeFilter() with an invalid scope error = nil")
+	}
+}
+
+func TestSubmissionResultFilter(t *testing.T) {
+	final := &model.JudgeResult{JudgeStatus: model.JudgeStatusAccepted}
+	judging := &model.JudgeResult{JudgeStatus: model.JudgeStatusJudging}
+	notFinal := bson.D{{Key: "$in", Value: []string{
+		model.JudgeStatusPending,
+		model.JudgeStatusQueued,
+		model.JudgeStatusJudging,
+	}}}
+
+	tests := []struct {
+		name      string
+		rejudgeID int64
+		result    *model.JudgeResult
+		rejudge   interface{}
+		status    interface{}
+	}{
+		{name: "first judge", result: final, rejudge: bson.D{{Key: "$exists", Value: false}}},
+		{name: "rejudge", rejudgeID: 7, result: final, rejudge: int64(7)},
+		{name: "intermediate", rejudgeID: 7, result: judging, rejudge: int64(7), status: notFinal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter := submissionResultFilter(1, tt.rejudgeID, tt.result)
+
+			if v, _ := lookup(filter, "submissionID"); v != int64(1) {
+				t.Errorf("submissionID = %v, want 1", v)
+			}
+			if v, _ := lookup(filter, "rejudgeID"); !reflect.DeepEqual(v, tt.rejudge) {
+				t.Errorf("rejudgeID = %v, want %v", v, tt.rejudge)
+			}
+			if v, _ := lookup(filter, "judgeStatus"); !reflect.DeepEqual(v, tt.status) {
+				t.Errorf("judgeStatus = %v, want %v", v, tt.status)
+			}
+		})
+	}
+}
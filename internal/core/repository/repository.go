@@ -1,6 +1,10 @@
 package repository
 
-import "github.com/SQL-Online-Judge/backend/internal/model"
+import (
+	"time"
+
+	"github.com/SQL-Online-Judge/backend/internal/model"
+)
 
 type UserRepository interface {
 	CreateUser(username, password, role string) (int64, error)
@@ -55,7 +59,9 @@ type AnswerRepository interface {
 	IsAnswerOfProblem(problemID, answerID int64) bool
 	UpdateAnswer(answer *model.Answer) error
 	FindAnswersByProblemID(problemID int64) ([]*model.Answer, error)
-	// FindByAnswerID(answerID int64) (*model.Answer, error)
+	FindByAnswerID(answerID int64) (*model.Answer, error)
+	FindUnreadyAnswers() ([]*model.Answer, error)
+	UpdateAnswerOutput(answerID, revision int64, answerOutputs []string, generateError string) error
 }
 
 type TaskRepository interface {
@@ -82,7 +88,23 @@ type SubmissionRepository interface {
 	CreateSubmission(s *model.Submission) (int64, error)
 	FindSubmissionsByStudentID(studentID int64) ([]*model.SubmissionSummary, error)
 	IsStudentSubmission(studentID, submission int64) bool
-	GetSubmittedSQL(submissionID int64) (string, error)
+	GetSubmittedSQL(submissionID int64) (*model.SubmitedSQL, error)
 	GetJudgeRequest(s *model.Submission) (*model.JudgeRequest, error)
 	UpdateSubmissionStatus(submissionID int64, status string) error
+	UpdateSubmissionResult(submissionID, rejudgeID int64, result *model.JudgeResult) (*model.Submission, error)
+	SweepPendingSubmissions() (int64, error)
+	ClaimSubmissionOutbox(lease time.Duration) (*model.Submission, error)
+	CompleteSubmissionOutbox(submissionID int64) (*model.Submission, error)
+	RetrySubmissionOutbox(submissionID int64, nextAttemptTime time.Time, lastError string) error
+	FailSubmissionOutbox(submissionID int64, output string) (*model.Submission, error)
+	FindSubmissionByID(submissionID int64) (*model.Submission, error)
+	RejudgeSubmissions(r *model.Rejudge) (int64, error)
+	CreateRejudge(r *model.Rejudge) (int64, error)
+	FindRejudgeByID(rejudgeID int64) (*model.Rejudge, error)
+	CountUnfinishedRejudgeSubmissions(rejudgeID int64) (int64, error)
+	FindLatestAcceptedSubmissions(taskID int64) ([]*model.SimilarSubmission, error)
+	CreateSimilarityReport(r *model.SimilarityReport) (int64, error)
+	FinishSimilarityReport(r *model.SimilarityReport) error
+	FindSimilarityReportByID(reportID int64) (*model.SimilarityReport, error)
+	FindLatestSimilarityReport(taskID int64) (*model.SimilarityReport, error)
 }
@@ -0,0 +1,145 @@
+package restapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SQL-Online-Judge/backend/internal/core/service"
+	"github.com/SQL-Online-Judge/backend/internal/model"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/logger"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+type similarSubmission struct {
+	SubmissionID string    `json:"submissionID"`
+	StudentID    string    `json:"studentID"`
+	Username     string    `json:"username"`
+	DBName       string    `json:"dbName"`
+	SubmitTime   time.Time `json:"submitTime"`
+	SubmittedSQL string    `json:"submittedSQL"`
+}
+
+type similarPair struct {
+	ProblemID   string               `json:"problemID"`
+	Similarity  float64              `json:"similarity"`
+	Submissions []*similarSubmission `json:"submissions"`
+}
+
+type similarityReport struct {
+	ReportID    string         `json:"reportID"`
+	TaskID      string         `json:"taskID"`
+	CreateTime  time.Time      `json:"createTime"`
+	FinishTime  *time.Time     `json:"finishTime,omitempty"`
+	Status      string         `json:"status"`
+	Error       string         `json:"error,omitempty"`
+	Submissions int64          `json:"submissions"`
+	Pairs       []*similarPair `json:"pairs"`
+}
+
+func newSimilarityReportFromModel(r *model.SimilarityReport) *similarityReport {
+	report := &similarityReport{
+		ReportID:    strconv.FormatInt(r.ReportID, 10),
+		TaskID:      strconv.FormatInt(r.TaskID, 10),
+		CreateTime:  r.CreateTime,
+		Status:      r.Status,
+		Error:       r.Error,
+		Submissions: r.Submissions,
+		Pairs:       make([]*similarPair, 0, len(r.Pairs)),
+	}
+	if !r.FinishTime.IsZero() {
+		report.FinishTime = &r.FinishTime
+	}
+
+	for _, p := range r.Pairs {
+		pair := &similarPair{
+			ProblemID:   strconv.FormatInt(p.ProblemID, 10),
+			Similarity:  p.Similarity,
+			Submissions: make([]*similarSubmission, 0, len(p.Submissions)),
+		}
+		for _, s := range p.Submissions {
+			pair.Submissions = append(pair.Submissions, &similarSubmission{
+				SubmissionID: strconv.FormatInt(s.SubmissionID, 10),
+				StudentID:    strconv.FormatInt(s.StudentID, 10),
+				Username:     s.Username,
+				DBName:       s.DBName,
+				SubmitTime:   s.SubmitTime,
+				SubmittedSQL: s.SubmittedSQL,
+			})
+		}
+		report.Pairs = append(report.Pairs, pair)
+	}
+
+	return report
+}
+
+type similarityResponse struct {
+	Report *similarityReport `json:"report,omitempty"`
+	Error  *errorResponse    `json:"error,omitempty"`
+}
+
+func (sr *similarityResponse) toJSON() []byte {
+	res, err := json.Marshal(sr)
+	if err != nil {
+		logger.Logger.Error("failed to marshal similarity response", zap.Error(err))
+		return nil
+	}
+
+	return res
+}
+
+func analyzeTaskSimilarity(w http.ResponseWriter, r *http.Request) {
+	requestID := getRequestID(r)
+	var resp similarityResponse
+
+	sTaskID := chi.URLParam(r, "taskID")
+	taskID, err := strconv.ParseInt(sTaskID, 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		resp.Error = &errorResponse{Code: http.StatusBadRequest, Message: "invalid task id"}
+		w.Write(resp.toJSON())
+		return
+	}
+
+	teacherID, ok := r.Context().Value(userIDKey).(int64)
+	if !ok {
+		logger.Logger.Error("failed to get teacher id from context", zap.String("requestID", requestID))
+		w.WriteHeader(http.StatusInternalServerError)
+		resp.Error = &errorResponse{Code: http.StatusInternalServerError, Message: "failed to get teacher id from context"}
+		w.Write(resp.toJSON())
+		return
+	}
+
+	report, err := submissionService.AnalyzeTaskSimilarity(taskService, teacherID, taskID)
+	if err == nil {
+		w.WriteHeader(http.StatusOK)
+		resp.Report = newSimilarityReportFromModel(report)
+		w.Write(resp.toJSON())
+		return
+	}
+
+	handleSimilarityError(w, &resp, err)
+}
+
+func handleSimilarityError(w http.ResponseWriter, resp *similarityResponse, err error) {
+	switch {
+	case errors.Is(err, service.ErrTaskNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		resp.Error = &errorResponse{Code: http.StatusNotFound, Message: "task not found"}
+	case errors.Is(err, service.ErrNotTaskAuthor):
+		w.WriteHeader(http.StatusForbidden)
+		resp.Error = &errorResponse{Code: http.StatusForbidden, Message: "not the author of the task"}
+	case errors.Is(err, service.ErrSimilarityReportNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		resp.Error = &errorResponse{Code: http.StatusNotFound, Message: "similarity report not found"}
+	default:
+		logger.Logger.Error("failed to analyze similarity", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		resp.Error = &errorResponse{Code: http.StatusInternalServerError, Message: "failed to analyze similarity"}
+	}
+
+	w.Write(resp.toJSON())
+}
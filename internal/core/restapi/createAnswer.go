@@ -14,10 +14,12 @@ import (
 )
 
 type createAnswerRequest struct {
-	DBName     string `json:"dbName"`
-	PrepareSQL string `json:"prepareSQL"`
-	AnswerSQL  string `json:"answerSQL"`
-	JudgeSQL   string `json:"judgeSQL"`
+	DBName         string                `json:"dbName"`
+	PrepareSQL     string                `json:"prepareSQL"`
+	AnswerSQL      string                `json:"answerSQL"`
+	JudgeSQL       string                `json:"judgeSQL"`
+	CompareOptions *model.CompareOptions `json:"compareOptions"`
+	Datasets       []*dataset            `json:"datasets"`
 }
 
 type createAnswerResponse struct {
@@ -65,11 +67,13 @@ func createAnswer(w http.ResponseWriter, r *http.Request) {
 	}
 
 	answer := model.NewAnswer(&model.Answer{
-		ProblemID:  problemID,
-		DBName:     req.DBName,
-		PrepareSQL: req.PrepareSQL,
-		AnswerSQL:  req.AnswerSQL,
-		JudgeSQL:   req.JudgeSQL,
+		ProblemID:      problemID,
+		DBName:         req.DBName,
+		PrepareSQL:     req.PrepareSQL,
+		AnswerSQL:      req.AnswerSQL,
+		JudgeSQL:       req.JudgeSQL,
+		CompareOptions: req.CompareOptions,
+		Datasets:       toDatasets(req.Datasets),
 	})
 
 	if !answer.IsValidAnswer() {
@@ -11,11 +11,17 @@ import (
 )
 
 type createProblemRequest struct {
-	Title       string   `json:"title"`
-	Tags        []string `json:"tags"`
-	Content     string   `json:"content"`
-	TimeLimit   int32    `json:"timeLimit"`
-	MemoryLimit int32    `json:"memoryLimit"`
+	Title               string           `json:"title"`
+	Tags                []string         `json:"tags"`
+	Content             string           `json:"content"`
+	TimeLimit           int32            `json:"timeLimit"`
+	MemoryLimit         int32            `json:"memoryLimit"`
+	OutputRowLimit      int32            `json:"outputRowLimit"`
+	OutputSizeLimit     int32            `json:"outputSizeLimit"`
+	DiffVisibility      string           `json:"diffVisibility"`
+	SQLPolicy           *model.SQLPolicy `json:"sqlPolicy"`
+	RequiredConstructs  []string         `json:"requiredConstructs"`
+	ForbiddenConstructs []string         `json:"forbiddenConstructs"`
 }
 
 type createProblemResponse struct {
@@ -55,12 +61,18 @@ func createProblem(w http.ResponseWriter, r *http.Request) {
 	}
 
 	problem := model.NewProblem(&model.Problem{
-		AuthorID:    authorID,
-		Title:       req.Title,
-		Tags:        req.Tags,
-		Content:     req.Content,
-		TimeLimit:   req.TimeLimit,
-		MemoryLimit: req.MemoryLimit,
+		AuthorID:            authorID,
+		Title:               req.Title,
+		Tags:                req.Tags,
+		Content:             req.Content,
+		TimeLimit:           req.TimeLimit,
+		MemoryLimit:         req.MemoryLimit,
+		OutputRowLimit:      req.OutputRowLimit,
+		OutputSizeLimit:     req.OutputSizeLimit,
+		DiffVisibility:      req.DiffVisibility,
+		SQLPolicy:           req.SQLPolicy,
+		RequiredConstructs:  req.RequiredConstructs,
+		ForbiddenConstructs: req.ForbiddenConstructs,
 	})
 
 	if !problem.IsValidProblem() {
@@ -0,0 +1,142 @@
+package restapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/SQL-Online-Judge/backend/internal/core/service"
+	"github.com/SQL-Online-Judge/backend/internal/model"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/logger"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+type createStudentRunRequest struct {
+	DBName       string `json:"dbName"`
+	SubmittedSQL string `json:"submittedSQL"`
+}
+
+type createStudentRunResponse struct {
+	JudgeStatus string         `json:"judgeStatus,omitempty"`
+	TimeCost    int32          `json:"timeCost,omitempty"`
+	Columns     []string       `json:"columns,omitempty"`
+	Rows        [][]*string    `json:"rows,omitempty"`
+	Truncated   bool           `json:"truncated,omitempty"`
+	Output      string         `json:"output,omitempty"`
+	Error       *errorResponse `json:"error,omitempty"`
+}
+
+func (csrr *createStudentRunResponse) toJSON() []byte {
+	res, err := json.Marshal(csrr)
+	if err != nil {
+		logger.Logger.Error("failed to marshal create student run response", zap.Error(err))
+		return nil
+	}
+	return res
+}
+
+func createStudentRun(w http.ResponseWriter, r *http.Request) {
+	requestID := getRequestID(r)
+	var resp createStudentRunResponse
+
+	var req createStudentRunRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		resp.Error = &errorResponse{Code: http.StatusBadRequest, Message: "failed to decode request body"}
+		w.Write(resp.toJSON())
+		return
+	}
+
+	sTaskID := chi.URLParam(r, "taskID")
+	taskID, err := strconv.ParseInt(sTaskID, 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		resp.Error = &errorResponse{Code: http.StatusBadRequest, Message: "failed to parse task id"}
+		w.Write(resp.toJSON())
+		return
+	}
+
+	sProblemID := chi.URLParam(r, "problemID")
+	problemID, err := strconv.ParseInt(sProblemID, 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		resp.Error = &errorResponse{Code: http.StatusBadRequest, Message: "invalid problem id"}
+		w.Write(resp.toJSON())
+		return
+	}
+
+	studentID, ok := r.Context().Value(userIDKey).(int64)
+	if !ok {
+		logger.Logger.Error("failed to get user id from context", zap.String("requestID", requestID))
+		w.WriteHeader(http.StatusInternalServerError)
+		resp.Error = &errorResponse{Code: http.StatusInternalServerError, Message: "failed to get user id from context"}
+		w.Write(resp.toJSON())
+		return
+	}
+
+	// a run is judged like a submission but never stored
+	submission := &model.Submission{
+		SubmitterID:  studentID,
+		TaskID:       taskID,
+		ProblemID:    problemID,
+		DBName:       req.DBName,
+		SubmittedSQL: req.SubmittedSQL,
+	}
+
+	if !submission.IsValidDBName() || !submission.IsValidSubmittedSQL() {
+		w.WriteHeader(http.StatusBadRequest)
+		resp.Error = &errorResponse{Code: http.StatusBadRequest, Message: "invalid run"}
+		w.Write(resp.toJSON())
+		return
+	}
+
+	result, err := taskService.RunStudentSQL(userService, problemService, answerService, submissionService, submission)
+	if err == nil {
+		resp.JudgeStatus = result.JudgeStatus
+		resp.TimeCost = result.TimeCost
+		resp.Columns = result.Columns
+		resp.Rows = result.Rows
+		resp.Truncated = result.Truncated
+		resp.Output = result.Output
+		w.WriteHeader(http.StatusOK)
+		w.Write(resp.toJSON())
+		return
+	}
+
+	switch {
+	case errors.Is(err, service.ErrTaskNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		resp.Error = &errorResponse{Code: http.StatusNotFound, Message: "task not found"}
+	case errors.Is(err, service.ErrCannotAccessTask):
+		w.WriteHeader(http.StatusForbidden)
+		resp.Error = &errorResponse{Code: http.StatusForbidden, Message: "cannot access task"}
+	case errors.Is(err, service.ErrTaskProblemNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		resp.Error = &errorResponse{Code: http.StatusNotFound, Message: "task problem not found"}
+	case errors.Is(err, service.ErrProblemNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		resp.Error = &errorResponse{Code: http.StatusNotFound, Message: "problem not found"}
+	case errors.Is(err, service.ErrNotInSubmitTime):
+		w.WriteHeader(http.StatusForbidden)
+		resp.Error = &errorResponse{Code: http.StatusForbidden, Message: "not in submit time"}
+	case errors.Is(err, service.ErrAnswerNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		resp.Error = &errorResponse{Code: http.StatusNotFound, Message: "the problem does not support the dialect"}
+	case errors.Is(err, service.ErrNoSampleDataset):
+		w.WriteHeader(http.StatusNotFound)
+		resp.Error = &errorResponse{Code: http.StatusNotFound, Message: "the problem has no sample dataset"}
+	case errors.Is(err, service.ErrRunRateLimited):
+		w.WriteHeader(http.StatusTooManyRequests)
+		resp.Error = &errorResponse{Code: http.StatusTooManyRequests, Message: "too many runs, try again later"}
+	case errors.Is(err, service.ErrRunTimeout):
+		w.WriteHeader(http.StatusGatewayTimeout)
+		resp.Error = &errorResponse{Code: http.StatusGatewayTimeout, Message: "no judger answered in time"}
+	default:
+		logger.Logger.Error("failed to run student sql", zap.Error(err), zap.String("requestID", requestID))
+		w.WriteHeader(http.StatusInternalServerError)
+		resp.Error = &errorResponse{Code: http.StatusInternalServerError, Message: "failed to run"}
+	}
+	w.Write(resp.toJSON())
+}
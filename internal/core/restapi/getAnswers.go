@@ -13,14 +13,59 @@ import (
 	"go.uber.org/zap"
 )
 
+type dataset struct {
+	PrepareSQL   string  `json:"prepareSQL"`
+	Weight       float64 `json:"weight"`
+	IsSample     bool    `json:"isSample"`
+	AnswerOutput string  `json:"answerOutput,omitempty"`
+}
+
+// toDatasets takes only the test data from the request, the outputs are
+// always generated by the judger.
+func toDatasets(datasets []*dataset) []*model.Dataset {
+	if len(datasets) == 0 {
+		return nil
+	}
+
+	res := make([]*model.Dataset, 0, len(datasets))
+	for _, d := range datasets {
+		if d == nil {
+			res = append(res, nil)
+			continue
+		}
+		res = append(res, &model.Dataset{
+			PrepareSQL: d.PrepareSQL,
+			Weight:     d.Weight,
+			IsSample:   d.IsSample,
+		})
+	}
+	return res
+}
+
+func newDatasetsFromModel(datasets []*model.Dataset) []*dataset {
+	res := make([]*dataset, 0, len(datasets))
+	for _, d := range datasets {
+		res = append(res, &dataset{
+			PrepareSQL:   d.PrepareSQL,
+			Weight:       d.GetWeight(),
+			IsSample:     d.IsSample,
+			AnswerOutput: d.AnswerOutput,
+		})
+	}
+	return res
+}
+
 type answer struct {
-	AnswerID     string `json:"answerID"`
-	DBName       string `json:"dbName"`
-	PrepareSQL   string `json:"prepareSQL"`
-	AnswerSQL    string `json:"answerSQL"`
-	JudgeSQL     string `json:"judgeSQL"`
-	AnswerOutput string `json:"answerOutput"`
-	IsReady      bool   `json:"isReady"`
+	AnswerID       string                `json:"answerID"`
+	DBName         string                `json:"dbName"`
+	PrepareSQL     string                `json:"prepareSQL"`
+	AnswerSQL      string                `json:"answerSQL"`
+	JudgeSQL       string                `json:"judgeSQL"`
+	CompareOptions *model.CompareOptions `json:"compareOptions"`
+	Datasets       []*dataset            `json:"datasets"`
+	AnswerOutput   string                `json:"answerOutput"`
+	IsReady        bool                  `json:"isReady"`
+	GenerateError  string                `json:"generateError,omitempty"`
 }
 
 type getAnswersResponse struct {
@@ -43,13 +88,16 @@ func (gar *getAnswersResponse) fromAnswers(answers []*model.Answer) {
 	gar.Answers = make([]*answer, 0, len(answers))
 	for _, a := range answers {
 		gar.Answers = append(gar.Answers, &answer{
-			AnswerID:     strconv.FormatInt(a.AnswerID, 10),
-			DBName:       a.DBName,
-			PrepareSQL:   a.PrepareSQL,
-			AnswerSQL:    a.AnswerSQL,
-			JudgeSQL:     a.JudgeSQL,
-			AnswerOutput: a.AnswerOutput,
-			IsReady:      a.IsReady,
+			AnswerID:       strconv.FormatInt(a.AnswerID, 10),
+			DBName:         a.DBName,
+			PrepareSQL:     a.PrepareSQL,
+			AnswerSQL:      a.AnswerSQL,
+			JudgeSQL:       a.JudgeSQL,
+			CompareOptions: a.CompareOptions,
+			Datasets:       newDatasetsFromModel(a.Datasets),
+			AnswerOutput:   a.AnswerOutput,
+			IsReady:        a.IsReady,
+			GenerateError:  a.GenerateError,
 		})
 	}
 }
@@ -0,0 +1,31 @@
+package restapi
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/SQL-Online-Judge/backend/internal/pkg/dialect"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/logger"
+	"go.uber.org/zap"
+)
+
+type getDialectsResponse struct {
+	Dialects []string       `json:"dialects"`
+	Error    *errorResponse `json:"error,omitempty"`
+}
+
+func (gdr *getDialectsResponse) toJSON() []byte {
+	res, err := json.Marshal(gdr)
+	if err != nil {
+		logger.Logger.Error("failed to marshal get dialects response", zap.Error(err))
+		return nil
+	}
+	return res
+}
+
+func getDialects(w http.ResponseWriter, r *http.Request) {
+	resp := getDialectsResponse{Dialects: dialect.Names()}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp.toJSON())
+}
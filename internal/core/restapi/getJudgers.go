@@ -0,0 +1,111 @@
+package restapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/SQL-Online-Judge/backend/internal/core/service"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/logger"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/mq"
+	"go.uber.org/zap"
+)
+
+type judger struct {
+	Name     string   `json:"name"`
+	DBNames  []string `json:"dbNames"`
+	Version  string   `json:"version"`
+	InFlight int64    `json:"inFlight"`
+	LastSeen string   `json:"lastSeen"`
+}
+
+type consumer struct {
+	Queue   string `json:"queue,omitempty"`
+	Name    string `json:"name"`
+	Pending int64  `json:"pending"`
+	// Idle is in milliseconds
+	Idle int64 `json:"idle"`
+}
+
+type queue struct {
+	Name      string      `json:"name"`
+	Priority  string      `json:"priority"`
+	Length    int64       `json:"length"`
+	Pending   int64       `json:"pending"`
+	Lag       int64       `json:"lag"`
+	Consumers []*consumer `json:"consumers"`
+}
+
+type getJudgersResponse struct {
+	Judgers        []*judger      `json:"judgers"`
+	Queues         []*queue       `json:"queues"`
+	StaleConsumers []*consumer    `json:"staleConsumers"`
+	Error          *errorResponse `json:"error,omitempty"`
+}
+
+func (gjr *getJudgersResponse) toJSON() []byte {
+	res, err := json.Marshal(gjr)
+	if err != nil {
+		logger.Logger.Error("failed to marshal get judgers response", zap.Error(err))
+		return nil
+	}
+	return res
+}
+
+func newConsumer(queueName string, cs *mq.ConsumerStats) *consumer {
+	return &consumer{
+		Queue:   queueName,
+		Name:    cs.Name,
+		Pending: cs.Pending,
+		Idle:    cs.Idle.Milliseconds(),
+	}
+}
+
+func getJudgers(w http.ResponseWriter, r *http.Request) {
+	requestID := getRequestID(r)
+	var resp getJudgersResponse
+
+	farm, err := service.GetJudgeFarm()
+	if err != nil {
+		logger.Logger.Error("failed to get judge farm", zap.String("requestID", requestID), zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		resp.Error = &errorResponse{Code: http.StatusInternalServerError, Message: "failed to get judgers"}
+		w.Write(resp.toJSON())
+		return
+	}
+
+	resp.Judgers = make([]*judger, 0, len(farm.Judgers))
+	for _, j := range farm.Judgers {
+		resp.Judgers = append(resp.Judgers, &judger{
+			Name:     j.Name,
+			DBNames:  j.DBNames,
+			Version:  j.Version,
+			InFlight: j.InFlight,
+			LastSeen: j.LastSeen.Format(time.RFC3339),
+		})
+	}
+
+	resp.Queues = make([]*queue, 0, len(farm.Queues))
+	for _, q := range farm.Queues {
+		consumers := make([]*consumer, 0, len(q.Consumers))
+		for _, cs := range q.Consumers {
+			consumers = append(consumers, newConsumer("", cs))
+		}
+		resp.Queues = append(resp.Queues, &queue{
+			Name:      q.Name,
+			Priority:  q.Priority.String(),
+			Length:    q.Length,
+			Pending:   q.Pending,
+			Lag:       q.Lag,
+			Consumers: consumers,
+		})
+	}
+
+	resp.StaleConsumers = make([]*consumer, 0, len(farm.StaleConsumers))
+	for _, sc := range farm.StaleConsumers {
+		resp.StaleConsumers = append(resp.StaleConsumers, newConsumer(sc.Queue, sc.ConsumerStats))
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(resp.toJSON())
+}
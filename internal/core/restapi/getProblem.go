@@ -7,19 +7,28 @@ import (
 	"strconv"
 
 	"github.com/SQL-Online-Judge/backend/internal/core/service"
+	"github.com/SQL-Online-Judge/backend/internal/model"
 	"github.com/SQL-Online-Judge/backend/internal/pkg/logger"
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 )
 
 type getProblemResponse struct {
-	ProblemID   string         `json:"problemID,omitempty"`
-	Title       string         `json:"title,omitempty"`
-	Tags        []string       `json:"tags,omitempty"`
-	Content     string         `json:"content,omitempty"`
-	TimeLimit   int32          `json:"timeLimit,omitempty"`
-	MemoryLimit int32          `json:"memoryLimit,omitempty"`
-	Error       *errorResponse `json:"error,omitempty"`
+	ProblemID       string   `json:"problemID,omitempty"`
+	Title           string   `json:"title,omitempty"`
+	Tags            []string `json:"tags,omitempty"`
+	Content         string   `json:"content,omitempty"`
+	TimeLimit       int32    `json:"timeLimit,omitempty"`
+	MemoryLimit     int32    `json:"memoryLimit,omitempty"`
+	OutputRowLimit  int32    `json:"outputRowLimit,omitempty"`
+	OutputSizeLimit int32    `json:"outputSizeLimit,omitempty"`
+	// DiffVisibility and SQLPolicy are shown to teachers only
+	DiffVisibility      string           `json:"diffVisibility,omitempty"`
+	SQLPolicy           *model.SQLPolicy `json:"sqlPolicy,omitempty"`
+	RequiredConstructs  []string         `json:"requiredConstructs,omitempty"`
+	ForbiddenConstructs []string         `json:"forbiddenConstructs,omitempty"`
+	Samples             []*sample        `json:"samples,omitempty"`
+	Error               *errorResponse   `json:"error,omitempty"`
 }
 
 func (gpr *getProblemResponse) toJSON() []byte {
@@ -52,6 +61,15 @@ func getProblem(w http.ResponseWriter, r *http.Request) {
 		resp.Content = problem.Content
 		resp.TimeLimit = problem.TimeLimit
 		resp.MemoryLimit = problem.MemoryLimit
+		resp.OutputRowLimit = problem.OutputRowLimit
+		resp.OutputSizeLimit = problem.OutputSizeLimit
+		resp.DiffVisibility = problem.DiffVisibility
+		resp.SQLPolicy = problem.SQLPolicy
+		resp.RequiredConstructs = problem.RequiredConstructs
+		resp.ForbiddenConstructs = problem.ForbiddenConstructs
+		if resp.DiffVisibility == "" {
+			resp.DiffVisibility = model.DiffVisibilitySummary
+		}
 
 		w.WriteHeader(http.StatusOK)
 		w.Write(resp.toJSON())
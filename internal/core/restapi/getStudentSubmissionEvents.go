@@ -0,0 +1,98 @@
+package restapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/SQL-Online-Judge/backend/internal/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const (
+	// eventWriteTimeout bounds each write to the event stream, which
+	// outlives the WriteTimeout of the server
+	eventWriteTimeout = 10 * time.Second
+	// eventKeepAlive is how often a comment is sent on an idle stream, so
+	// proxies do not close it
+	eventKeepAlive = 15 * time.Second
+)
+
+type getStudentSubmissionEventsResponse struct {
+	Error *errorResponse `json:"error,omitempty"`
+}
+
+func (gsser *getStudentSubmissionEventsResponse) toJSON() []byte {
+	res, err := json.Marshal(gsser)
+	if err != nil {
+		logger.Logger.Error("failed to marshal get student submission events response", zap.Error(err))
+		return nil
+	}
+	return res
+}
+
+// getStudentSubmissionEvents streams the status changes of the submissions
+// of the student as server-sent events, one "status" event each. Only the
+// changes after the stream is opened are sent.
+func getStudentSubmissionEvents(w http.ResponseWriter, r *http.Request) {
+	requestID := getRequestID(r)
+	var resp getStudentSubmissionEventsResponse
+
+	studentID, ok := r.Context().Value(userIDKey).(int64)
+	if !ok {
+		logger.Logger.Error("failed to get student id from context", zap.String("requestID", requestID))
+		w.WriteHeader(http.StatusInternalServerError)
+		resp.Error = &errorResponse{Code: http.StatusInternalServerError, Message: "failed to get student id from context"}
+		w.Write(resp.toJSON())
+		return
+	}
+
+	statuses, err := submissionService.SubscribeSubmissionStatus(r.Context(), studentID)
+	if err != nil {
+		logger.Logger.Error("failed to subscribe submission status", zap.String("requestID", requestID), zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		resp.Error = &errorResponse{Code: http.StatusInternalServerError, Message: "failed to subscribe submission status"}
+		w.Write(resp.toJSON())
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event string) error {
+		err := rc.SetWriteDeadline(time.Now().Add(eventWriteTimeout))
+		if err != nil && !errors.Is(err, http.ErrNotSupported) {
+			return err
+		}
+		_, err = fmt.Fprint(w, event)
+		if err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	err = send(": connected\n\n")
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+
+	for err == nil {
+		select {
+		case <-r.Context().Done():
+			return
+		case status, ok := <-statuses:
+			if !ok {
+				return
+			}
+			err = send("event: status\ndata: " + status + "\n\n")
+		case <-keepAlive.C:
+			err = send(": keep-alive\n\n")
+		}
+	}
+
+	logger.Logger.Info("submission event stream closed", zap.String("requestID", requestID), zap.Error(err))
+}
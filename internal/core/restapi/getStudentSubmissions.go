@@ -20,6 +20,7 @@ type submissionSummary struct {
 	DBName       string `json:"dbName"`
 	JudgeStatus  string `json:"judgeStatus"`
 	TimeCost     int32  `json:"timeCost"`
+	Score        string `json:"score"`
 }
 
 type getStudentSubmissionsResponse struct {
@@ -70,6 +71,7 @@ func getStudentSubmissions(w http.ResponseWriter, r *http.Request) {
 			DBName:       submission.DBName,
 			JudgeStatus:  submission.JudgeStatus,
 			TimeCost:     submission.TimeCost,
+			Score:        strconv.FormatFloat(submission.Score, 'f', -1, 64),
 		})
 	}
 
@@ -7,15 +7,48 @@ import (
 	"strconv"
 
 	"github.com/SQL-Online-Judge/backend/internal/core/service"
+	"github.com/SQL-Online-Judge/backend/internal/model"
 	"github.com/SQL-Online-Judge/backend/internal/pkg/logger"
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 )
 
+// datasetResult is the result of a submission on a dataset. Students see
+// everything about a sample dataset but only whether a hidden one passed.
+type datasetResult struct {
+	IsSample     bool        `json:"isSample"`
+	Passed       bool        `json:"passed"`
+	JudgeStatus  string      `json:"judgeStatus,omitempty"`
+	TimeCost     int32       `json:"timeCost,omitempty"`
+	JudgerOutput string      `json:"judgerOutput,omitempty"`
+	Diff         *model.Diff `json:"diff,omitempty"`
+}
+
+func newStudentDatasetResultsFromModel(results []*model.DatasetResult) []*datasetResult {
+	res := make([]*datasetResult, 0, len(results))
+	for _, r := range results {
+		dr := &datasetResult{
+			IsSample: r.IsSample,
+			Passed:   r.JudgeStatus == model.JudgeStatusAccepted,
+		}
+		if r.IsSample {
+			dr.JudgeStatus = r.JudgeStatus
+			dr.TimeCost = r.TimeCost
+			dr.JudgerOutput = r.JudgerOutput
+			dr.Diff = r.Diff
+		}
+		res = append(res, dr)
+	}
+	return res
+}
+
 type getStudentSubmittedSQLResponse struct {
-	SubmissionID string         `json:"submissionID,omitempty"`
-	SubmittedSQL string         `json:"submittedSQL,omitempty"`
-	Error        *errorResponse `json:"error,omitempty"`
+	SubmissionID string           `json:"submissionID,omitempty"`
+	SubmittedSQL string           `json:"submittedSQL,omitempty"`
+	JudgeStatus  string           `json:"judgeStatus,omitempty"`
+	JudgerOutput string           `json:"judgerOutput,omitempty"`
+	Datasets     []*datasetResult `json:"datasets,omitempty"`
+	Error        *errorResponse   `json:"error,omitempty"`
 }
 
 func (gsssr *getStudentSubmittedSQLResponse) toJSON() []byte {
@@ -49,10 +82,13 @@ func getStudentSubmittedSQL(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	sql, err := submissionService.GetStudentSubmittedSQL(studentID, submissionID)
+	submittedSQL, err := submissionService.GetStudentSubmittedSQL(problemService, studentID, submissionID)
 	if err == nil {
 		resp.SubmissionID = sSubmissionID
-		resp.SubmittedSQL = sql
+		resp.SubmittedSQL = submittedSQL.SubmittedSQL
+		resp.JudgeStatus = submittedSQL.JudgeStatus
+		resp.JudgerOutput = submittedSQL.JudgerOutput
+		resp.Datasets = newStudentDatasetResultsFromModel(submittedSQL.DatasetResults)
 
 		w.WriteHeader(http.StatusOK)
 		w.Write(resp.toJSON())
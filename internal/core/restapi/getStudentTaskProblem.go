@@ -6,11 +6,30 @@ import (
 	"strconv"
 
 	"github.com/SQL-Online-Judge/backend/internal/core/service"
+	"github.com/SQL-Online-Judge/backend/internal/model"
 	"github.com/SQL-Online-Judge/backend/internal/pkg/logger"
 	"github.com/go-chi/chi/v5"
 	"go.uber.org/zap"
 )
 
+type sample struct {
+	DBName         string `json:"dbName"`
+	PrepareSQL     string `json:"prepareSQL"`
+	ExpectedOutput string `json:"expectedOutput"`
+}
+
+func newSamplesFromModel(samples []*model.Sample) []*sample {
+	res := make([]*sample, 0, len(samples))
+	for _, s := range samples {
+		res = append(res, &sample{
+			DBName:         s.DBName,
+			PrepareSQL:     s.PrepareSQL,
+			ExpectedOutput: s.AnswerOutput,
+		})
+	}
+	return res
+}
+
 func getStudentTaskProblem(w http.ResponseWriter, r *http.Request) {
 	requestID := getRequestID(r)
 	var resp getProblemResponse
@@ -44,12 +63,26 @@ func getStudentTaskProblem(w http.ResponseWriter, r *http.Request) {
 
 	problem, err := taskService.GetStudentTaskProblem(userService, problemService, studentID, taskID, problemID)
 	if err == nil {
+		samples, err := answerService.GetSamples(problemID)
+		if err != nil {
+			logger.Logger.Error("failed to get samples", zap.Error(err), zap.String("requestID", requestID))
+			w.WriteHeader(http.StatusInternalServerError)
+			resp.Error = &errorResponse{Code: http.StatusInternalServerError, Message: "internal server error"}
+			w.Write(resp.toJSON())
+			return
+		}
+
 		resp.ProblemID = sProblemID
 		resp.Title = problem.Title
 		resp.Tags = problem.Tags
 		resp.Content = problem.Content
 		resp.TimeLimit = problem.TimeLimit
 		resp.MemoryLimit = problem.MemoryLimit
+		resp.OutputRowLimit = problem.OutputRowLimit
+		resp.OutputSizeLimit = problem.OutputSizeLimit
+		resp.RequiredConstructs = problem.RequiredConstructs
+		resp.ForbiddenConstructs = problem.ForbiddenConstructs
+		resp.Samples = newSamplesFromModel(samples)
 
 		w.WriteHeader(http.StatusOK)
 		w.Write(resp.toJSON())
@@ -0,0 +1,53 @@
+package restapi
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/SQL-Online-Judge/backend/internal/pkg/dialect"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/logger"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+func rejudgeProblem(w http.ResponseWriter, r *http.Request) {
+	requestID := getRequestID(r)
+	var resp rejudgeResponse
+
+	sProblemID := chi.URLParam(r, "problemID")
+	problemID, err := strconv.ParseInt(sProblemID, 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		resp.Error = &errorResponse{Code: http.StatusBadRequest, Message: "invalid problem id"}
+		w.Write(resp.toJSON())
+		return
+	}
+
+	// an empty dbName rejudges the problem in every dialect
+	dbName := r.URL.Query().Get("dbName")
+	if dbName != "" && !dialect.IsSupported(dbName) {
+		w.WriteHeader(http.StatusBadRequest)
+		resp.Error = &errorResponse{Code: http.StatusBadRequest, Message: "invalid db name"}
+		w.Write(resp.toJSON())
+		return
+	}
+
+	teacherID, ok := r.Context().Value(userIDKey).(int64)
+	if !ok {
+		logger.Logger.Error("failed to get teacher id from context", zap.String("requestID", requestID))
+		w.WriteHeader(http.StatusInternalServerError)
+		resp.Error = &errorResponse{Code: http.StatusInternalServerError, Message: "failed to get teacher id from context"}
+		w.Write(resp.toJSON())
+		return
+	}
+
+	rj, err := submissionService.RejudgeProblem(problemService, teacherID, problemID, dbName)
+	if err == nil {
+		w.WriteHeader(http.StatusOK)
+		resp.Rejudge = newRejudgeFromModel(rj)
+		w.Write(resp.toJSON())
+		return
+	}
+
+	handleRejudgeError(w, &resp, err)
+}
@@ -0,0 +1,120 @@
+package restapi
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/SQL-Online-Judge/backend/internal/core/service"
+	"github.com/SQL-Online-Judge/backend/internal/model"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/logger"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+type rejudge struct {
+	RejudgeID  string    `json:"rejudgeID"`
+	CreateTime time.Time `json:"createTime"`
+	Scope      string    `json:"scope"`
+	TargetID   string    `json:"targetID"`
+	DBName     string    `json:"dbName,omitempty"`
+	Total      int64     `json:"total"`
+	Done       int64     `json:"done"`
+}
+
+func newRejudgeFromModel(r *model.Rejudge) *rejudge {
+	return &rejudge{
+		RejudgeID:  strconv.FormatInt(r.RejudgeID, 10),
+		CreateTime: r.CreateTime,
+		Scope:      r.Scope,
+		TargetID:   strconv.FormatInt(r.TargetID, 10),
+		DBName:     r.DBName,
+		Total:      r.Total,
+		Done:       r.Done,
+	}
+}
+
+type rejudgeResponse struct {
+	Rejudge *rejudge       `json:"rejudge,omitempty"`
+	Error   *errorResponse `json:"error,omitempty"`
+}
+
+func (rr *rejudgeResponse) toJSON() []byte {
+	res, err := json.Marshal(rr)
+	if err != nil {
+		logger.Logger.Error("failed to marshal rejudge response", zap.Error(err))
+		return nil
+	}
+
+	return res
+}
+
+func rejudgeSubmission(w http.ResponseWriter, r *http.Request) {
+	requestID := getRequestID(r)
+	var resp rejudgeResponse
+
+	sSubmissionID := chi.URLParam(r, "submissionID")
+	submissionID, err := strconv.ParseInt(sSubmissionID, 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		resp.Error = &errorResponse{Code: http.StatusBadRequest, Message: "invalid submission id"}
+		w.Write(resp.toJSON())
+		return
+	}
+
+	teacherID, ok := r.Context().Value(userIDKey).(int64)
+	if !ok {
+		logger.Logger.Error("failed to get teacher id from context", zap.String("requestID", requestID))
+		w.WriteHeader(http.StatusInternalServerError)
+		resp.Error = &errorResponse{Code: http.StatusInternalServerError, Message: "failed to get teacher id from context"}
+		w.Write(resp.toJSON())
+		return
+	}
+
+	rj, err := submissionService.RejudgeSubmission(taskService, problemService, teacherID, submissionID)
+	if err == nil {
+		w.WriteHeader(http.StatusOK)
+		resp.Rejudge = newRejudgeFromModel(rj)
+		w.Write(resp.toJSON())
+		return
+	}
+
+	handleRejudgeError(w, &resp, err)
+}
+
+func handleRejudgeError(w http.ResponseWriter, resp *rejudgeResponse, err error) {
+	switch {
+	case errors.Is(err, service.ErrSubmissionNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		resp.Error = &errorResponse{Code: http.StatusNotFound, Message: "submission not found"}
+	case errors.Is(err, service.ErrNotSubmissionJudger):
+		w.WriteHeader(http.StatusForbidden)
+		resp.Error = &errorResponse{Code: http.StatusForbidden, Message: "not the author of the task or the problem"}
+	case errors.Is(err, service.ErrSubmissionNotRejudgeable):
+		w.WriteHeader(http.StatusConflict)
+		resp.Error = &errorResponse{Code: http.StatusConflict, Message: "submission is being judged or was never judged"}
+	case errors.Is(err, service.ErrProblemNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		resp.Error = &errorResponse{Code: http.StatusNotFound, Message: "problem not found"}
+	case errors.Is(err, service.ErrNotProblemAuthor):
+		w.WriteHeader(http.StatusForbidden)
+		resp.Error = &errorResponse{Code: http.StatusForbidden, Message: "not the problem author"}
+	case errors.Is(err, service.ErrTaskNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		resp.Error = &errorResponse{Code: http.StatusNotFound, Message: "task not found"}
+	case errors.Is(err, service.ErrNotTaskAuthor):
+		w.WriteHeader(http.StatusForbidden)
+		resp.Error = &errorResponse{Code: http.StatusForbidden, Message: "not the author of the task"}
+	case errors.Is(err, service.ErrRejudgeNotFound):
+		w.WriteHeader(http.StatusNotFound)
+		resp.Error = &errorResponse{Code: http.StatusNotFound, Message: "rejudge not found"}
+	default:
+		logger.Logger.Error("failed to rejudge", zap.Error(err))
+		w.WriteHeader(http.StatusInternalServerError)
+		resp.Error = &errorResponse{Code: http.StatusInternalServerError, Message: "failed to rejudge"}
+	}
+
+	w.Write(resp.toJSON())
+}
@@ -0,0 +1,43 @@
+package restapi
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/SQL-Online-Judge/backend/internal/pkg/logger"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+func rejudgeTask(w http.ResponseWriter, r *http.Request) {
+	requestID := getRequestID(r)
+	var resp rejudgeResponse
+
+	sTaskID := chi.URLParam(r, "taskID")
+	taskID, err := strconv.ParseInt(sTaskID, 10, 64)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		resp.Error = &errorResponse{Code: http.StatusBadRequest, Message: "invalid task id"}
+		w.Write(resp.toJSON())
+		return
+	}
+
+	teacherID, ok := r.Context().Value(userIDKey).(int64)
+	if !ok {
+		logger.Logger.Error("failed to get teacher id from context", zap.String("requestID", requestID))
+		w.WriteHeader(http.StatusInternalServerError)
+		resp.Error = &errorResponse{Code: http.StatusInternalServerError, Message: "failed to get teacher id from context"}
+		w.Write(resp.toJSON())
+		return
+	}
+
+	rj, err := submissionService.RejudgeTask(taskService, teacherID, taskID)
+	if err == nil {
+		w.WriteHeader(http.StatusOK)
+		resp.Rejudge = newRejudgeFromModel(rj)
+		w.Write(resp.toJSON())
+		return
+	}
+
+	handleRejudgeError(w, &resp, err)
+}
@@ -24,10 +24,13 @@ func NewRouter() *chi.Mux {
 		r.Use(jwtauth.Authenticator(tokenAuth))
 		r.Use(getRole)
 
+		r.Get("/dialects", getDialects)
+
 		r.Route("/admin", func(r chi.Router) {
 			r.Group(func(r chi.Router) {
 				r.Use(checkRole("admin"))
 				r.Post("/teacher", createTeacher)
+				r.Get("/judgers", getJudgers)
 			})
 
 			r.Group(func(r chi.Router) {
@@ -58,6 +61,7 @@ func NewRouter() *chi.Mux {
 				r.Delete("/problems/{problemID}/answers/{answerID}", deleteAnswer)
 				r.Put("/problems/{problemID}/answers/{answerID}", updateAnswer)
 				r.Get("/problems/{problemID}/answers", getAnswers)
+				r.Post("/problems/{problemID}/rejudge", rejudgeProblem)
 
 				r.Post("/tasks", createTask)
 				r.Delete("/tasks/{taskID}", deleteTask)
@@ -67,6 +71,12 @@ func NewRouter() *chi.Mux {
 				r.Get("/tasks/{taskID}", getTask)
 				r.Get("/tasks", getTasks)
 				r.Get("/my/tasks", getTeacherTasks)
+				r.Post("/tasks/{taskID}/rejudge", rejudgeTask)
+				r.Post("/tasks/{taskID}/similarity", analyzeTaskSimilarity)
+				r.Get("/tasks/{taskID}/similarity", getTaskSimilarity)
+
+				r.Post("/submissions/{submissionID}/rejudge", rejudgeSubmission)
+				r.Get("/rejudges/{rejudgeID}", getRejudge)
 
 				r.Post("/classes/{classID}/tasks", addTasksToClass)
 				r.Delete("/classes/{classID}/tasks", removeTasksFromClass)
@@ -81,8 +91,10 @@ func NewRouter() *chi.Mux {
 			r.Get("/tasks/{taskID}/problems", getStudentTaskProblems)
 			r.Get("/tasks/{taskID}/problems/{problemID}", getStudentTaskProblem)
 			r.Post("/tasks/{taskID}/problems/{problemID}/submissions", createStudentSubmission)
+			r.Post("/tasks/{taskID}/problems/{problemID}/runs", createStudentRun)
 
 			r.Get("/submissions", getStudentSubmissions)
+			r.Get("/submissions/events", getStudentSubmissionEvents)
 			r.Get("/submissions/{submissionID}", getStudentSubmittedSQL)
 		})
 	})
@@ -14,16 +14,20 @@ import (
 )
 
 type upadateAnswerRequest struct {
-	PrepareSQL string `json:"prepareSQL"`
-	AnswerSQL  string `json:"answerSQL"`
-	JudgeSQL   string `json:"judgeSQL"`
+	PrepareSQL     string                `json:"prepareSQL"`
+	AnswerSQL      string                `json:"answerSQL"`
+	JudgeSQL       string                `json:"judgeSQL"`
+	CompareOptions *model.CompareOptions `json:"compareOptions"`
+	Datasets       []*dataset            `json:"datasets"`
 }
 
 func (uar *upadateAnswerRequest) toAnswer() *model.Answer {
 	answer := &model.Answer{
-		PrepareSQL: uar.PrepareSQL,
-		AnswerSQL:  uar.AnswerSQL,
-		JudgeSQL:   uar.JudgeSQL,
+		PrepareSQL:     uar.PrepareSQL,
+		AnswerSQL:      uar.AnswerSQL,
+		JudgeSQL:       uar.JudgeSQL,
+		CompareOptions: uar.CompareOptions,
+		Datasets:       toDatasets(uar.Datasets),
 	}
 
 	return answer
@@ -31,7 +35,7 @@ func (uar *upadateAnswerRequest) toAnswer() *model.Answer {
 
 func (uar *upadateAnswerRequest) isValid() bool {
 	answer := uar.toAnswer()
-	return answer.IsValidPrepareSQL() && answer.IsValidAnswerSQL() && answer.IsValidJudgeSQL()
+	return answer.IsValidPrepareSQL() && answer.IsValidAnswerSQL() && answer.IsValidJudgeSQL() && answer.IsValidCompareOptions() && answer.IsValidDatasets()
 }
 
 type upadateAnswerResponse struct {
@@ -14,11 +14,17 @@ import (
 )
 
 type updateProblemRequest struct {
-	Title       string   `json:"title"`
-	Tags        []string `json:"tags"`
-	Content     string   `json:"content"`
-	TimeLimit   int32    `json:"timeLimit"`
-	MemoryLimit int32    `json:"memoryLimit"`
+	Title               string           `json:"title"`
+	Tags                []string         `json:"tags"`
+	Content             string           `json:"content"`
+	TimeLimit           int32            `json:"timeLimit"`
+	MemoryLimit         int32            `json:"memoryLimit"`
+	OutputRowLimit      int32            `json:"outputRowLimit"`
+	OutputSizeLimit     int32            `json:"outputSizeLimit"`
+	DiffVisibility      string           `json:"diffVisibility"`
+	SQLPolicy           *model.SQLPolicy `json:"sqlPolicy"`
+	RequiredConstructs  []string         `json:"requiredConstructs"`
+	ForbiddenConstructs []string         `json:"forbiddenConstructs"`
 }
 
 type updateProblemResponse struct {
@@ -67,13 +73,19 @@ func updateProblem(w http.ResponseWriter, r *http.Request) {
 	}
 
 	problem := &model.Problem{
-		ProblemID:   problemID,
-		AuthorID:    teacherID,
-		Title:       req.Title,
-		Tags:        req.Tags,
-		Content:     req.Content,
-		TimeLimit:   req.TimeLimit,
-		MemoryLimit: req.MemoryLimit,
+		ProblemID:           problemID,
+		AuthorID:            teacherID,
+		Title:               req.Title,
+		Tags:                req.Tags,
+		Content:             req.Content,
+		TimeLimit:           req.TimeLimit,
+		MemoryLimit:         req.MemoryLimit,
+		OutputRowLimit:      req.OutputRowLimit,
+		OutputSizeLimit:     req.OutputSizeLimit,
+		DiffVisibility:      req.DiffVisibility,
+		SQLPolicy:           req.SQLPolicy,
+		RequiredConstructs:  req.RequiredConstructs,
+		ForbiddenConstructs: req.ForbiddenConstructs,
 	}
 
 	if !problem.IsValidProblem() {
@@ -2,15 +2,20 @@ package service
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/SQL-Online-Judge/backend/internal/core/repository"
 	"github.com/SQL-Online-Judge/backend/internal/model"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/logger"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/mq"
+	"go.uber.org/zap"
 )
 
 var (
-	ErrAnswerNotFound     = fmt.Errorf("answer not found")
-	ErrAnswerAlreadyExist = fmt.Errorf("answer already exist")
-	ErrNotAnswerOfProblem = fmt.Errorf("not the answer of the problem")
+	ErrAnswerNotFound      = fmt.Errorf("answer not found")
+	ErrAnswerAlreadyExist  = fmt.Errorf("answer already exist")
+	ErrNotAnswerOfProblem  = fmt.Errorf("not the answer of the problem")
+	ErrInvalidAnswerOutput = fmt.Errorf("invalid answer output")
 )
 
 type AnswerService struct {
@@ -63,6 +68,8 @@ func (as *AnswerService) CreateAnswer(teacherID int64, ps *ProblemService, answe
 		return 0, fmt.Errorf("failed to create answer: %w", err)
 	}
 
+	as.generateAnswerOutput(answer, mq.PriorityHigh)
+
 	return answerID, nil
 }
 
@@ -121,6 +128,14 @@ func (as *AnswerService) UpdateAnswer(teacherID int64, ps *ProblemService, answe
 	if err != nil {
 		return fmt.Errorf("failed to update answer: %w", err)
 	}
+	invalidateVerdicts(problemID)
+
+	updated, err := as.repo.FindByAnswerID(answerID)
+	if err != nil {
+		logger.Logger.Error("failed to find updated answer", zap.Int64("answerID", answerID), zap.Error(err))
+		return nil
+	}
+	as.generateAnswerOutput(updated, mq.PriorityHigh)
 
 	return nil
 }
@@ -141,3 +156,68 @@ func (as *AnswerService) GetAnswers(ps *ProblemService, problemID int64) ([]*mod
 
 	return answers, nil
 }
+
+// generateAnswerOutput asks a judger to run the answer and report its output.
+// A failure is only logged: the answer stays unready and is picked up again
+// by RegenerateUnreadyAnswers. A teacher waiting for the output asks with
+// PriorityHigh.
+func (as *AnswerService) generateAnswerOutput(answer *model.Answer, priority mq.Priority) {
+	reqJSON, err := answer.ToGenerateRequest().ToJSON()
+	if err != nil {
+		logger.Logger.Error("failed to marshal answer generate request", zap.Int64("answerID", answer.AnswerID), zap.Error(err))
+		return
+	}
+
+	err = MQService.EnqueueWithPriority(mq.QueueAnswerGenerate, reqJSON, priority)
+	if err != nil {
+		logger.Logger.Error("failed to enqueue answer generate request", zap.Int64("answerID", answer.AnswerID), zap.Error(err))
+	}
+}
+
+func (as *AnswerService) RegenerateUnreadyAnswers() error {
+	answers, err := as.repo.FindUnreadyAnswers()
+	if err != nil {
+		return fmt.Errorf("failed to get unready answers: %w", err)
+	}
+
+	for _, answer := range answers {
+		as.generateAnswerOutput(answer, mq.PriorityNormal)
+	}
+
+	return nil
+}
+
+func (as *AnswerService) UpdateAnswerOutput(resp *model.AnswerGenerateResponse) error {
+	answerID, err := strconv.ParseInt(resp.AnswerID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid answer id %q", ErrInvalidAnswerOutput, resp.AnswerID)
+	}
+
+	answerOutputs := resp.AnswerOutputs
+	if len(answerOutputs) == 0 && resp.AnswerOutput != "" {
+		// sent by a judger that knows nothing about datasets
+		answerOutputs = []string{resp.AnswerOutput}
+	}
+
+	err = as.repo.UpdateAnswerOutput(answerID, resp.Revision, answerOutputs, resp.Error)
+	if err != nil {
+		return fmt.Errorf("failed to update answer output: %w", err)
+	}
+
+	return nil
+}
+
+// GetSamples returns the sample datasets of a problem in every dialect.
+func (as *AnswerService) GetSamples(problemID int64) ([]*model.Sample, error) {
+	answers, err := as.repo.FindAnswersByProblemID(problemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get answers: %w", err)
+	}
+
+	var samples []*model.Sample
+	for _, answer := range answers {
+		samples = append(samples, answer.GetSamples()...)
+	}
+
+	return samples, nil
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/SQL-Online-Judge/backend/internal/pkg/db/redis"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/heartbeat"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/mq"
+)
+
+// staleConsumerIdle is how long a consumer may go without reading its queue
+// before it is taken for dead; a live one reads every few seconds.
+const staleConsumerIdle = time.Minute
+
+var judgerRegistry = heartbeat.NewRegistry(redis.GetRedisDB())
+
+var queues = []string{mq.QueueAnswerGenerate, mq.QueueAnswerOutput, mq.QueueSubmission, mq.QueueJudgeResult, mq.QueueRun, mq.QueueSimilarity}
+
+// StaleConsumer is a dead consumer still holding messages, which are
+// delivered again once they are reclaimed.
+type StaleConsumer struct {
+	Queue string
+	*mq.ConsumerStats
+}
+
+// JudgeFarm is the state of the judgers and of the queues between them and
+// the core, with a QueueStats for each lane.
+type JudgeFarm struct {
+	Judgers        []*heartbeat.Judger
+	Queues         []*mq.QueueStats
+	StaleConsumers []*StaleConsumer
+}
+
+func GetJudgeFarm() (*JudgeFarm, error) {
+	judgers, err := judgerRegistry.List()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list judgers: %w", err)
+	}
+
+	farm := &JudgeFarm{Judgers: judgers}
+	for _, queue := range queues {
+		lanes, err := MQService.Stats(queue)
+		if err != nil {
+			return nil, err
+		}
+		farm.Queues = append(farm.Queues, lanes...)
+
+		for _, lane := range lanes {
+			lane.Consumers = pruneConsumers(lane.Name, lane.Consumers)
+			for _, consumer := range lane.Consumers {
+				if consumer.Idle > staleConsumerIdle {
+					farm.StaleConsumers = append(farm.StaleConsumers, &StaleConsumer{Queue: lane.Name, ConsumerStats: consumer})
+				}
+			}
+		}
+	}
+
+	return farm, nil
+}
+
+// pruneConsumers deletes the dead consumers of lane that hold no messages,
+// so that the consumers of stopped judgers are not listed forever, and
+// returns the others. The mq keeps a consumer that got a message meanwhile.
+func pruneConsumers(lane string, consumers []*mq.ConsumerStats) []*mq.ConsumerStats {
+	kept := consumers[:0]
+	for _, consumer := range consumers {
+		if consumer.Idle > staleConsumerIdle && consumer.Pending == 0 {
+			if MQService.DeleteConsumer(lane, consumer.Name) == nil {
+				continue
+			}
+		}
+		kept = append(kept, consumer)
+	}
+	return kept
+}
@@ -81,6 +81,7 @@ func (ps *ProblemService) UpdateProblem(p *model.Problem) error {
 	if err != nil {
 		return fmt.Errorf("failed to update problem: %w", err)
 	}
+	invalidateVerdicts(p.ProblemID)
 
 	return nil
 }
@@ -0,0 +1,120 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/SQL-Online-Judge/backend/internal/model"
+)
+
+var (
+	ErrRejudgeNotFound          = fmt.Errorf("rejudge not found")
+	ErrNotSubmissionJudger      = fmt.Errorf("neither the author of the task nor of the problem of the submission")
+	ErrSubmissionNotRejudgeable = fmt.Errorf("submission is being judged or was never judged")
+)
+
+func (ss *SubmissionService) rejudge(r *model.Rejudge) (*model.Rejudge, error) {
+	rejudge := model.NewRejudge(r)
+
+	total, err := ss.repo.RejudgeSubmissions(rejudge)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rejudge submissions: %w", err)
+	}
+	rejudge.Total = total
+
+	_, err = ss.repo.CreateRejudge(rejudge)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create rejudge: %w", err)
+	}
+
+	notifySubmissionOutbox()
+	return rejudge, nil
+}
+
+// RejudgeSubmission re-runs a single submission. The teacher must be the
+// author of its task or of its problem.
+func (ss *SubmissionService) RejudgeSubmission(ts *TaskService, ps *ProblemService, teacherID, submissionID int64) (*model.Rejudge, error) {
+	submission, err := ss.repo.FindSubmissionByID(submissionID)
+	if err != nil {
+		return nil, fmt.Errorf("%w", ErrSubmissionNotFound)
+	}
+
+	if !ts.checkTaskAuthor(teacherID, submission.TaskID) && !ps.checkProblemAuthor(teacherID, submission.ProblemID) {
+		return nil, fmt.Errorf("%w", ErrNotSubmissionJudger)
+	}
+
+	if !submission.IsRejudgeable() {
+		return nil, fmt.Errorf("%w", ErrSubmissionNotRejudgeable)
+	}
+
+	return ss.rejudge(&model.Rejudge{
+		CreatorID: teacherID,
+		Scope:     model.RejudgeScopeSubmission,
+		TargetID:  submissionID,
+	})
+}
+
+// RejudgeProblem re-runs every submission of a problem, or only those in
+// dbName when it is not empty.
+func (ss *SubmissionService) RejudgeProblem(ps *ProblemService, teacherID, problemID int64, dbName string) (*model.Rejudge, error) {
+	if !ps.isProblemIDExist(problemID) {
+		return nil, fmt.Errorf("%w", ErrProblemNotFound)
+	}
+
+	if ps.isProblemDeleted(problemID) {
+		return nil, fmt.Errorf("%w", ErrProblemNotFound)
+	}
+
+	if !ps.checkProblemAuthor(teacherID, problemID) {
+		return nil, fmt.Errorf("%w", ErrNotProblemAuthor)
+	}
+
+	return ss.rejudge(&model.Rejudge{
+		CreatorID: teacherID,
+		Scope:     model.RejudgeScopeProblem,
+		TargetID:  problemID,
+		DBName:    dbName,
+	})
+}
+
+// RejudgeTask re-runs every submission of a task.
+func (ss *SubmissionService) RejudgeTask(ts *TaskService, teacherID, taskID int64) (*model.Rejudge, error) {
+	if !ts.isTaskIDExist(taskID) {
+		return nil, fmt.Errorf("%w", ErrTaskNotFound)
+	}
+
+	if ts.isTaskDeleted(taskID) {
+		return nil, fmt.Errorf("%w", ErrTaskNotFound)
+	}
+
+	if !ts.checkTaskAuthor(teacherID, taskID) {
+		return nil, fmt.Errorf("%w", ErrNotTaskAuthor)
+	}
+
+	return ss.rejudge(&model.Rejudge{
+		CreatorID: teacherID,
+		Scope:     model.RejudgeScopeTask,
+		TargetID:  taskID,
+	})
+}
+
+// GetRejudge returns a rejudge of the teacher with its progress. A
+// submission counts as done once it has a verdict, or once a later rejudge
+// took it over.
+func (ss *SubmissionService) GetRejudge(teacherID, rejudgeID int64) (*model.Rejudge, error) {
+	rejudge, err := ss.repo.FindRejudgeByID(rejudgeID)
+	if err != nil {
+		return nil, fmt.Errorf("%w", ErrRejudgeNotFound)
+	}
+
+	if rejudge.CreatorID != teacherID {
+		return nil, fmt.Errorf("%w", ErrRejudgeNotFound)
+	}
+
+	unfinished, err := ss.repo.CountUnfinishedRejudgeSubmissions(rejudgeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rejudge progress: %w", err)
+	}
+	rejudge.Done = rejudge.Total - unfinished
+
+	return rejudge, nil
+}
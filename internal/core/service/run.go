@@ -0,0 +1,126 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/SQL-Online-Judge/backend/internal/model"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/db/redis"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/id"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/mq"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/ratelimit"
+)
+
+var (
+	ErrNoSampleDataset = fmt.Errorf("no sample dataset")
+	ErrRunRateLimited  = fmt.Errorf("too many runs")
+	ErrRunTimeout      = fmt.Errorf("run timed out")
+)
+
+const (
+	runRateLimit  = 10
+	runRateWindow = time.Minute
+	// runMaxTimeLimit caps the time limit of a run, so that the response
+	// is written before the server times out
+	runMaxTimeLimit = 10000 // in milliseconds
+	// runReplyGrace is how long a run may wait for a judger on top of the
+	// time limit of the problem
+	runReplyGrace = 10 * time.Second
+)
+
+// runLimiter limits the runs of each student, apart from the submissions.
+var runLimiter = ratelimit.NewLimiter(redis.GetRedisDB(), "ratelimit:run", runRateLimit, runRateWindow)
+
+// RunStudentSQL runs the SQL of submission on the first sample dataset of
+// the answer in its dialect and waits for the output. Nothing is recorded.
+// Like a submission, a run is only allowed within the submit time of the
+// task.
+func (ts *TaskService) RunStudentSQL(us *UserService, ps *ProblemService, as *AnswerService, ss *SubmissionService, submission *model.Submission) (*model.RunResult, error) {
+	if err := ts.canStudentAccessTask(us, submission.SubmitterID, submission.TaskID); err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	if !ts.isTaskProblem(submission.TaskID, submission.ProblemID) {
+		return nil, fmt.Errorf("%w", ErrTaskProblemNotFound)
+	}
+
+	if !ps.isProblemIDExist(submission.ProblemID) || ps.isProblemDeleted(submission.ProblemID) {
+		return nil, fmt.Errorf("%w", ErrProblemNotFound)
+	}
+
+	if !ts.isInSubmitTime(submission.TaskID) {
+		return nil, fmt.Errorf("%w", ErrNotInSubmitTime)
+	}
+
+	if !as.isAnswerExist(submission.ProblemID, submission.DBName) {
+		return nil, fmt.Errorf("%w", ErrAnswerNotFound)
+	}
+
+	allowed, err := runLimiter.Allow(strconv.FormatInt(submission.SubmitterID, 10))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check run rate: %w", err)
+	}
+	if !allowed {
+		return nil, fmt.Errorf("%w", ErrRunRateLimited)
+	}
+
+	judgeRequest, err := ss.repo.GetJudgeRequest(submission)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get judge request: %w", err)
+	}
+
+	dataset := judgeRequest.Answer.GetSampleDataset()
+	if dataset == nil {
+		return nil, fmt.Errorf("%w", ErrNoSampleDataset)
+	}
+
+	if judgeRequest.Problem.TimeLimit > runMaxTimeLimit {
+		judgeRequest.Problem.TimeLimit = runMaxTimeLimit
+	}
+
+	timeout := time.Duration(judgeRequest.Problem.TimeLimit)*time.Millisecond + runReplyGrace
+	runID := strconv.FormatInt(id.NewID(), 10)
+	req := &model.RunRequest{
+		RunID:        runID,
+		ReplyTo:      mq.ReplyChannel(runID),
+		Deadline:     time.Now().Add(timeout),
+		MaxRows:      model.MaxRunRows,
+		SubmittedSQL: submission.SubmittedSQL,
+		Problem:      judgeRequest.Problem,
+		Answer:       judgeRequest.Answer,
+		Dataset:      dataset,
+	}
+	// the judger needs neither the outputs nor the other datasets
+	req.Answer.AnswerOutput = ""
+	req.Answer.Datasets = nil
+	req.Dataset.AnswerOutput = ""
+
+	reqJSON, err := req.ToJSON()
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal run request: %w", err)
+	}
+
+	// a student is waiting for the reply
+	err = MQService.EnqueueWithPriority(mq.QueueRun, reqJSON, mq.PriorityHigh)
+	if err != nil {
+		return nil, fmt.Errorf("failed to enqueue run request: %w", err)
+	}
+
+	resultJSON, err := MQService.AwaitReply(req.ReplyTo, timeout)
+	if errors.Is(err, mq.ErrNoReply) {
+		return nil, fmt.Errorf("%w", ErrRunTimeout)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to await run result: %w", err)
+	}
+
+	var result model.RunResult
+	err = result.FromJSON(resultJSON)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal run result: %w", err)
+	}
+
+	return &result, nil
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/SQL-Online-Judge/backend/internal/model"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/dialect"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/logger"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/mq"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/sqlparse"
+	"go.uber.org/zap"
+)
+
+var ErrSimilarityReportNotFound = fmt.Errorf("similarity report not found")
+
+const (
+	// shingleSize is the number of tokens in a shingle, the unit two SQL
+	// are compared by
+	shingleSize = 4
+	// similarityThreshold is the similarity from which a pair is reported
+	similarityThreshold = 0.8
+	// a shingle in more than commonShingleShare of the submissions of a
+	// problem is what any solution looks like, not a sign of copying; it is
+	// ignored once the problem has minCommonSubmissions submissions
+	commonShingleShare   = 0.5
+	minCommonSubmissions = 5
+)
+
+// AnalyzeTaskSimilarity starts looking for copied submissions in a task.
+// The report is filled in by the similarity worker.
+func (ss *SubmissionService) AnalyzeTaskSimilarity(ts *TaskService, teacherID, taskID int64) (*model.SimilarityReport, error) {
+	if !ts.isTaskIDExist(taskID) {
+		return nil, fmt.Errorf("%w", ErrTaskNotFound)
+	}
+
+	if ts.isTaskDeleted(taskID) {
+		return nil, fmt.Errorf("%w", ErrTaskNotFound)
+	}
+
+	if !ts.checkTaskAuthor(teacherID, taskID) {
+		return nil, fmt.Errorf("%w", ErrNotTaskAuthor)
+	}
+
+	report := model.NewSimilarityReport(&model.SimilarityReport{
+		TaskID:    taskID,
+		CreatorID: teacherID,
+	})
+	_, err := ss.repo.CreateSimilarityReport(report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create similarity report: %w", err)
+	}
+
+	err = MQService.Enqueue(mq.QueueSimilarity, strconv.FormatInt(report.ReportID, 10))
+	if err != nil {
+		report.Status = model.SimilarityStatusFailed
+		report.Error = "failed to start the analysis"
+		report.FinishTime = time.Now()
+		ss.repo.FinishSimilarityReport(report)
+		return nil, fmt.Errorf("failed to enqueue similarity analysis: %w", err)
+	}
+
+	return report, nil
+}
+
+// GetTaskSimilarity returns the last similarity report of a task.
+func (ss *SubmissionService) GetTaskSimilarity(ts *TaskService, teacherID, taskID int64) (*model.SimilarityReport, error) {
+	if !ts.isTaskIDExist(taskID) {
+		return nil, fmt.Errorf("%w", ErrTaskNotFound)
+	}
+
+	if !ts.checkTaskAuthor(teacherID, taskID) {
+		return nil, fmt.Errorf("%w", ErrNotTaskAuthor)
+	}
+
+	report, err := ss.repo.FindLatestSimilarityReport(taskID)
+	if err != nil {
+		return nil, fmt.Errorf("%w", ErrSimilarityReportNotFound)
+	}
+
+	return report, nil
+}
+
+// RunSimilarityAnalysis fills in a running similarity report. A report that
+// is not running any more was filled in already.
+func (ss *SubmissionService) RunSimilarityAnalysis(reportID int64) error {
+	report, err := ss.repo.FindSimilarityReportByID(reportID)
+	if err != nil {
+		return fmt.Errorf("%w", ErrSimilarityReportNotFound)
+	}
+
+	if report.Status != model.SimilarityStatusRunning {
+		return nil
+	}
+
+	submissions, err := ss.repo.FindLatestAcceptedSubmissions(report.TaskID)
+	if err != nil {
+		logger.Logger.Error("failed to analyze similarity", zap.Int64("reportID", reportID), zap.Error(err))
+		report.Status = model.SimilarityStatusFailed
+		report.Error = "failed to get the accepted submissions"
+	} else {
+		report.Status = model.SimilarityStatusDone
+		report.Submissions = int64(len(submissions))
+		report.Pairs = findSimilarPairs(submissions)
+	}
+	report.FinishTime = time.Now()
+
+	err = ss.repo.FinishSimilarityReport(report)
+	if err != nil {
+		return fmt.Errorf("failed to finish similarity report: %w", err)
+	}
+
+	return nil
+}
+
+// findSimilarPairs compares the submissions of each problem pairwise and
+// returns the pairs from similarityThreshold on, the most similar first.
+func findSimilarPairs(submissions []*model.SimilarSubmission) []*model.SimilarPair {
+	problems := make(map[int64][]*model.SimilarSubmission)
+	for _, s := range submissions {
+		problems[s.ProblemID] = append(problems[s.ProblemID], s)
+	}
+
+	pairs := []*model.SimilarPair{}
+	for problemID, submissions := range problems {
+		shingles := make([]map[string]bool, len(submissions))
+		for i, s := range submissions {
+			opts, ok := dialect.Options(s.DBName)
+			if !ok {
+				// a submission read as the wrong dialect would be matched by
+				// noise, so it is compared with nothing
+				logger.Logger.Warn("unknown dialect of submission", zap.Int64("submissionID", s.SubmissionID), zap.String("dbName", s.DBName))
+				shingles[i] = map[string]bool{}
+				continue
+			}
+			shingles[i] = shinglesOf(sqlparse.Skeleton(s.SubmittedSQL, opts))
+		}
+		dropCommonShingles(shingles)
+
+		for i := range submissions {
+			for j := i + 1; j < len(submissions); j++ {
+				similarity := jaccard(shingles[i], shingles[j])
+				if similarity < similarityThreshold {
+					continue
+				}
+				pairs = append(pairs, &model.SimilarPair{
+					ProblemID:   problemID,
+					Similarity:  similarity,
+					Submissions: []*model.SimilarSubmission{submissions[i], submissions[j]},
+				})
+			}
+		}
+	}
+
+	sort.SliceStable(pairs, func(i, j int) bool {
+		if pairs[i].Similarity != pairs[j].Similarity {
+			return pairs[i].Similarity > pairs[j].Similarity
+		}
+		return pairs[i].ProblemID < pairs[j].ProblemID
+	})
+	return pairs
+}
+
+// shinglesOf returns the runs of shingleSize tokens in skeleton, or the
+// whole skeleton if it is shorter.
+func shinglesOf(skeleton []string) map[string]bool {
+	shingles := make(map[string]bool)
+	if len(skeleton) > 0 && len(skeleton) < shingleSize {
+		shingles[strings.Join(skeleton, " ")] = true
+	}
+	for i := 0; i+shingleSize <= len(skeleton); i++ {
+		shingles[strings.Join(skeleton[i:i+shingleSize], " ")] = true
+	}
+	return shingles
+}
+
+func dropCommonShingles(shingles []map[string]bool) {
+	if len(shingles) < minCommonSubmissions {
+		return
+	}
+
+	counts := make(map[string]int)
+	for _, set := range shingles {
+		for shingle := range set {
+			counts[shingle]++
+		}
+	}
+	for _, set := range shingles {
+		for shingle := range set {
+			if float64(counts[shingle]) > commonShingleShare*float64(len(shingles)) {
+				delete(set, shingle)
+			}
+		}
+	}
+}
+
+// jaccard returns the share of the shingles of a and b that both have, 0
+// if they have none.
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for shingle := range a {
+		if b[shingle] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
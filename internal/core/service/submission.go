@@ -1,8 +1,12 @@
 package service
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
+	"time"
 
+	"github.com/SQL-Online-Judge/backend/internal/core/outbox"
 	"github.com/SQL-Online-Judge/backend/internal/core/repository"
 	"github.com/SQL-Online-Judge/backend/internal/model"
 	"github.com/SQL-Online-Judge/backend/internal/pkg/logger"
@@ -12,8 +16,26 @@ import (
 
 var (
 	ErrSubmissionNotFound = fmt.Errorf("submission not found")
+	ErrInvalidJudgeResult = fmt.Errorf("invalid judge result")
+	ErrAnswerNotReady     = fmt.Errorf("answer is not ready yet")
 )
 
+// submissionOutbox wakes the outbox relay up when a submission is created.
+var submissionOutbox = make(chan struct{}, 1)
+
+func notifySubmissionOutbox() {
+	select {
+	case submissionOutbox <- struct{}{}:
+	default:
+	}
+}
+
+// SubmissionOutboxNotified returns a channel that receives after a
+// submission is created.
+func SubmissionOutboxNotified() <-chan struct{} {
+	return submissionOutbox
+}
+
 type SubmissionService struct {
 	repo repository.SubmissionRepository
 }
@@ -24,39 +46,63 @@ func NewSubmissionService(sr repository.SubmissionRepository) *SubmissionService
 	}
 }
 
-func (ss *SubmissionService) CreateSubmission(submission *model.Submission) (int64, error) {
+// CreateSubmission stores the submission together with its outbox entry;
+// the outbox relay then publishes the judge request with priority. A
+// submission of SQL already judged against the same answer gets the cached
+// result instead and is never published.
+func (ss *SubmissionService) CreateSubmission(submission *model.Submission, priority mq.Priority) (int64, error) {
+	submission.Outbox = &model.SubmissionOutbox{NextAttemptTime: time.Now(), Priority: int(priority)}
+
+	judgeRequest, err := ss.repo.GetJudgeRequest(submission)
+	if err != nil {
+		logger.Logger.Warn("failed to get judge request, skip the verdict cache", zap.Error(err))
+	} else if judgeRequest.Answer.IsReady {
+		if result := lookupVerdict(submission.ProblemID, judgeRequest.Answer, submission.Fingerprint); result != nil {
+			return ss.createJudgedSubmission(submission, result)
+		}
+	}
+
 	submissionID, err := ss.repo.CreateSubmission(submission)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create submission: %w", err)
 	}
 
-	go func() {
-		judgeRequest, err := ss.repo.GetJudgeRequest(submission)
-		if err != nil {
-			logger.Logger.Error("failed to get judge request", zap.Error(err))
-			return
-		}
+	publishSubmissionStatus(submission)
+	notifySubmissionOutbox()
+	return submissionID, nil
+}
 
-		if !judgeRequest.Answer.IsReady {
-			logger.Logger.Error("answer is not ready yet")
-			return
-		}
+// CreateRejectedSubmission stores a submission that is rejected before
+// judging with its final status and output; it is never published.
+func (ss *SubmissionService) CreateRejectedSubmission(submission *model.Submission, status, output string) (int64, error) {
+	submission.JudgeStatus = status
+	submission.JudgerOutput = output
 
-		judgeRequestJSON, err := judgeRequest.ToJSON()
-		if err != nil {
-			logger.Logger.Error("failed to marshal judge request", zap.Error(err))
-			return
-		}
+	submissionID, err := ss.repo.CreateSubmission(submission)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create submission: %w", err)
+	}
 
-		err = MQService.Enqueue(mq.QueueSubmission, judgeRequestJSON)
-		if err != nil {
-			logger.Logger.Error("failed to enqueue submission", zap.Error(err))
-			return
-		}
+	publishSubmissionStatus(submission)
+	return submissionID, nil
+}
 
-		ss.repo.UpdateSubmissionStatus(submissionID, model.JudgeStatusQueued)
-	}()
+// createJudgedSubmission stores a submission with the result it would be
+// judged to; it is never published.
+func (ss *SubmissionService) createJudgedSubmission(submission *model.Submission, result *model.JudgeResult) (int64, error) {
+	submission.Outbox = nil
+	submission.JudgeStatus = result.JudgeStatus
+	submission.TimeCost = result.TimeCost
+	submission.JudgerOutput = result.JudgerOutput
+	submission.Score = result.Score
+	submission.DatasetResults = result.Datasets
+
+	submissionID, err := ss.repo.CreateSubmission(submission)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create submission: %w", err)
+	}
 
+	publishSubmissionStatus(submission)
 	return submissionID, nil
 }
 
@@ -73,15 +119,138 @@ func (ss *SubmissionService) isStudentSubmission(studentID, submissionID int64)
 	return ss.repo.IsStudentSubmission(studentID, submissionID)
 }
 
-func (ss *SubmissionService) GetStudentSubmittedSQL(studentID, submissionID int64) (string, error) {
+// GetStudentSubmittedSQL returns the submitted SQL with the results on
+// every dataset as the student may see them: only the verdict of a hidden
+// dataset, and the diff of a sample dataset at the visibility level of the
+// problem.
+func (ss *SubmissionService) GetStudentSubmittedSQL(ps *ProblemService, studentID, submissionID int64) (*model.SubmitedSQL, error) {
 	if !ss.isStudentSubmission(studentID, submissionID) {
-		return "", fmt.Errorf("%w", ErrSubmissionNotFound)
+		return nil, fmt.Errorf("%w", ErrSubmissionNotFound)
+	}
+
+	submittedSQL, err := ss.repo.GetSubmittedSQL(submissionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get student submitted SQL: %w", err)
+	}
+
+	problem, err := ps.repo.FindByProblemID(submittedSQL.ProblemID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get problem: %w", err)
+	}
+
+	// the output of the submission may come from a hidden dataset, only the
+	// reason of a rejection is safe to show
+	switch submittedSQL.JudgeStatus {
+	case model.JudgeStatusPolicyViolation, model.JudgeStatusConstructViolation, model.JudgeStatusCompileError:
+	default:
+		submittedSQL.JudgerOutput = ""
+	}
+
+	// results judged before the implicit dataset of an answer without
+	// datasets was hidden are marked as samples, so they are only trusted
+	// when the answer has datasets of its own
+	hasSamples := false
+	judgeRequest, err := ss.repo.GetJudgeRequest(&model.Submission{ProblemID: submittedSQL.ProblemID})
+	if err != nil {
+		logger.Logger.Warn("failed to get answer, hide every dataset", zap.Int64("submissionID", submissionID), zap.Error(err))
+	} else {
+		hasSamples = len(judgeRequest.Answer.Datasets) > 0
+	}
+
+	for i, result := range submittedSQL.DatasetResults {
+		if !result.IsSample || !hasSamples {
+			submittedSQL.DatasetResults[i] = &model.DatasetResult{JudgeStatus: result.JudgeStatus}
+			continue
+		}
+
+		if result.Diff != nil {
+			// JudgerOutput holds the whole diff
+			result.JudgerOutput = ""
+			result.Diff = result.Diff.Filter(problem.DiffVisibility)
+		}
+	}
+
+	return submittedSQL, nil
+}
+
+func (ss *SubmissionService) UpdateSubmissionResult(resp *model.JudgeResponse) error {
+	submissionID, err := strconv.ParseInt(resp.SubmissionID, 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: invalid submission id %q", ErrInvalidJudgeResult, resp.SubmissionID)
+	}
+
+	if resp.Result == nil {
+		return fmt.Errorf("%w: result is nil", ErrInvalidJudgeResult)
+	}
+
+	s := &model.Submission{JudgeStatus: resp.Result.JudgeStatus}
+	if !s.IsValidJudgeStatus() {
+		return fmt.Errorf("%w: invalid judge status %q", ErrInvalidJudgeResult, resp.Result.JudgeStatus)
+	}
+
+	submission, err := ss.repo.UpdateSubmissionResult(submissionID, resp.RejudgeID, resp.Result)
+	if errors.Is(err, repository.ErrSubmissionNotUpdated) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update submission result: %w", err)
+	}
+
+	publishSubmissionStatus(submission)
+	storeVerdict(submission, resp)
+	return nil
+}
+
+// SweepPendingSubmissions hands the pending submissions without an outbox
+// entry to the outbox relay.
+func (ss *SubmissionService) SweepPendingSubmissions() error {
+	count, err := ss.repo.SweepPendingSubmissions()
+	if err != nil {
+		return fmt.Errorf("failed to sweep pending submissions: %w", err)
+	}
+
+	if count > 0 {
+		logger.Logger.Info("swept pending submissions into the outbox", zap.Int64("count", count))
+	}
+	return nil
+}
+
+// RelaySubmissionOutbox publishes every due outbox entry to the submission
+// queue, see outbox.Relay.
+func (ss *SubmissionService) RelaySubmissionOutbox() error {
+	return outbox.NewRelay(ss.repo, ss.publishSubmission, publishSubmissionStatus).Run()
+}
+
+// publishSubmission builds the judge request of the submission from the
+// current answer and publishes it.
+func (ss *SubmissionService) publishSubmission(submission *model.Submission) error {
+	requestTime := time.Now()
+	judgeRequest, err := ss.repo.GetJudgeRequest(submission)
+	if errors.Is(err, repository.ErrAnswerNotFound) {
+		return fmt.Errorf("%w: %w", outbox.ErrCannotJudge, err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get judge request: %w", err)
+	}
+	judgeRequest.RequestTime = requestTime
+
+	// only an answer still being generated may become ready
+	if judgeRequest.Answer.GenerateError != "" {
+		return fmt.Errorf("%w: the answer failed to generate", outbox.ErrCannotJudge)
+	}
+	if !judgeRequest.Answer.IsReady {
+		return fmt.Errorf("%w", ErrAnswerNotReady)
+	}
+
+	judgeRequestJSON, err := judgeRequest.ToJSON()
+	if err != nil {
+		return fmt.Errorf("failed to marshal judge request: %w", err)
 	}
 
-	sql, err := ss.repo.GetSubmittedSQL(submissionID)
+	err = MQService.EnqueueWithPriority(mq.QueueSubmission, judgeRequestJSON, mq.Priority(submission.Outbox.Priority))
 	if err != nil {
-		return "", fmt.Errorf("failed to get student submitted SQL: %w", err)
+		return fmt.Errorf("failed to enqueue submission: %w", err)
 	}
 
-	return sql, nil
+	return nil
 }
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/SQL-Online-Judge/backend/internal/model"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/logger"
+	"go.uber.org/zap"
+)
+
+// submissionStatusChannel is where the status changes of the submissions of
+// a student are published, for every core to pass on to the student.
+func submissionStatusChannel(studentID int64) string {
+	return "submission-status:" + strconv.FormatInt(studentID, 10)
+}
+
+// publishSubmissionStatus tells the submitter the current status of
+// submission. It is best effort: a student who missed it sees the status in
+// the list of their submissions.
+func publishSubmissionStatus(submission *model.Submission) {
+	status, err := model.NewSubmissionStatus(submission).ToJSON()
+	if err != nil {
+		logger.Logger.Warn("failed to marshal submission status", zap.Int64("submissionID", submission.SubmissionID), zap.Error(err))
+		return
+	}
+
+	err = MQService.Publish(submissionStatusChannel(submission.SubmitterID), status)
+	if err != nil {
+		logger.Logger.Warn("failed to publish submission status", zap.Int64("submissionID", submission.SubmissionID), zap.Error(err))
+	}
+}
+
+// SubscribeSubmissionStatus returns the status changes of the submissions
+// of the student from now on, as JSON, until ctx is done.
+func (ss *SubmissionService) SubscribeSubmissionStatus(ctx context.Context, studentID int64) (<-chan string, error) {
+	statuses, err := MQService.Subscribe(ctx, submissionStatusChannel(studentID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to subscribe submission status: %w", err)
+	}
+
+	return statuses, nil
+}
@@ -5,6 +5,7 @@ import (
 
 	"github.com/SQL-Online-Judge/backend/internal/core/repository"
 	"github.com/SQL-Online-Judge/backend/internal/model"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/mq"
 )
 
 var (
@@ -303,7 +304,33 @@ func (ts *TaskService) CreateStudentSubmission(us *UserService, ps *ProblemServi
 		return 0, fmt.Errorf("%w", ErrNotInSubmitTime)
 	}
 
-	submissionID, err := ss.CreateSubmission(submission)
+	problem, err := ps.repo.FindByProblemID(submission.ProblemID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get problem: %w", err)
+	}
+
+	err = problem.SQLPolicy.Check(submission.SubmittedSQL)
+	if err != nil {
+		// the submission is kept, so the student sees why it was rejected
+		submissionID, err := ss.CreateRejectedSubmission(submission, model.JudgeStatusPolicyViolation, err.Error())
+		if err != nil {
+			return 0, fmt.Errorf("failed to create submission: %w", err)
+		}
+		return submissionID, nil
+	}
+
+	task, err := ts.repo.FindByTaskID(submission.TaskID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get task: %w", err)
+	}
+
+	// exams are judged before practice
+	priority := mq.PriorityNormal
+	if task.IsTimeLimited {
+		priority = mq.PriorityHigh
+	}
+
+	submissionID, err := ss.CreateSubmission(submission, priority)
 	if err != nil {
 		return 0, fmt.Errorf("failed to create submission: %w", err)
 	}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/SQL-Online-Judge/backend/internal/model"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/cache"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/db/redis"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/logger"
+	"go.uber.org/zap"
+)
+
+const verdictTTL = 24 * time.Hour
+
+// verdictCache holds the results of the submissions judged, grouped by
+// problem, so that a resubmission of the same SQL is not judged again. The
+// limits and policies of the problem are part of the result too, so
+// updating the problem or any of its answers invalidates the group.
+var verdictCache = cache.NewCache(redis.GetRedisDB(), "verdict", verdictTTL)
+
+func verdictKey(answerID, revision int64, fingerprint string) string {
+	return fmt.Sprintf("%d:%d:%s", answerID, revision, fingerprint)
+}
+
+// lookupVerdict returns the cached result of SQL with fingerprint judged
+// against the revision of the answer, or nil.
+func lookupVerdict(problemID int64, answer *model.JudgeAnswer, fingerprint string) *model.JudgeResult {
+	if answer.AnswerID == 0 || fingerprint == "" {
+		return nil
+	}
+
+	resultJSON, err := verdictCache.Get(strconv.FormatInt(problemID, 10), verdictKey(answer.AnswerID, answer.Revision, fingerprint))
+	if errors.Is(err, cache.ErrCacheMiss) {
+		return nil
+	}
+	if err != nil {
+		logger.Logger.Warn("failed to look up verdict", zap.Int64("problemID", problemID), zap.Error(err))
+		return nil
+	}
+
+	var result model.JudgeResult
+	err = json.Unmarshal([]byte(resultJSON), &result)
+	if err != nil {
+		logger.Logger.Warn("drop invalid cached verdict", zap.Int64("problemID", problemID), zap.Error(err))
+		return nil
+	}
+
+	return &result
+}
+
+// storeVerdict caches the result of submission if judging it again would
+// give the same. A judge request built before the last invalidation may
+// hold the old problem, so its result is not stored. Responses without a
+// request time fall back to the submit time.
+func storeVerdict(submission *model.Submission, resp *model.JudgeResponse) {
+	if resp.AnswerID == 0 || submission.Fingerprint == "" || !resp.Result.IsReproducible() {
+		return
+	}
+
+	resultJSON, err := json.Marshal(resp.Result)
+	if err != nil {
+		logger.Logger.Warn("failed to marshal verdict", zap.Int64("submissionID", submission.SubmissionID), zap.Error(err))
+		return
+	}
+
+	asOf := resp.RequestTime
+	if asOf.IsZero() {
+		asOf = submission.SubmitTime
+	}
+
+	group := strconv.FormatInt(submission.ProblemID, 10)
+	err = verdictCache.Set(group, verdictKey(resp.AnswerID, resp.Revision, submission.Fingerprint), string(resultJSON), asOf)
+	if err != nil {
+		logger.Logger.Warn("failed to store verdict", zap.Int64("submissionID", submission.SubmissionID), zap.Error(err))
+	}
+}
+
+func invalidateVerdicts(problemID int64) {
+	err := verdictCache.Invalidate(strconv.FormatInt(problemID, 10))
+	if err != nil {
+		logger.Logger.Error("failed to invalidate verdicts", zap.Int64("problemID", problemID), zap.Error(err))
+	}
+}
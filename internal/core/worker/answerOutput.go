@@ -0,0 +1,31 @@
+package worker
+
+import (
+	"errors"
+
+	"github.com/SQL-Online-Judge/backend/internal/core/service"
+	"github.com/SQL-Online-Judge/backend/internal/model"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/logger"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/mq"
+	"go.uber.org/zap"
+)
+
+func handleAnswerOutput(msg *mq.Msg) error {
+	var resp model.AnswerGenerateResponse
+	err := resp.FromJSON(msg.Data)
+	if err != nil {
+		logger.Logger.Error("drop invalid answer generate response", zap.String("msgID", msg.ID), zap.Error(err))
+		return nil
+	}
+
+	err = answerService.UpdateAnswerOutput(&resp)
+	if errors.Is(err, service.ErrInvalidAnswerOutput) {
+		logger.Logger.Error("drop invalid answer generate response", zap.String("msgID", msg.ID), zap.Error(err))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
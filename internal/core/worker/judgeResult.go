@@ -0,0 +1,31 @@
+package worker
+
+import (
+	"errors"
+
+	"github.com/SQL-Online-Judge/backend/internal/core/service"
+	"github.com/SQL-Online-Judge/backend/internal/model"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/logger"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/mq"
+	"go.uber.org/zap"
+)
+
+func handleJudgeResult(msg *mq.Msg) error {
+	var resp model.JudgeResponse
+	err := resp.FromJSON(msg.Data)
+	if err != nil {
+		logger.Logger.Error("drop invalid judge response", zap.String("msgID", msg.ID), zap.Error(err))
+		return nil
+	}
+
+	err = submissionService.UpdateSubmissionResult(&resp)
+	if errors.Is(err, service.ErrInvalidJudgeResult) {
+		logger.Logger.Error("drop invalid judge response", zap.String("msgID", msg.ID), zap.Error(err))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
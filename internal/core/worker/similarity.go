@@ -0,0 +1,30 @@
+package worker
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/SQL-Online-Judge/backend/internal/core/service"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/logger"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/mq"
+	"go.uber.org/zap"
+)
+
+func handleSimilarity(msg *mq.Msg) error {
+	reportID, err := strconv.ParseInt(msg.Data, 10, 64)
+	if err != nil {
+		logger.Logger.Error("drop invalid similarity request", zap.String("msgID", msg.ID), zap.Error(err))
+		return nil
+	}
+
+	err = submissionService.RunSimilarityAnalysis(reportID)
+	if errors.Is(err, service.ErrSimilarityReportNotFound) {
+		logger.Logger.Error("drop similarity request of unknown report", zap.String("msgID", msg.ID), zap.Int64("reportID", reportID))
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
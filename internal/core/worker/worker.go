@@ -0,0 +1,123 @@
+package worker
+
+import (
+	"errors"
+	"os"
+	"time"
+
+	"github.com/SQL-Online-Judge/backend/internal/core/repository"
+	"github.com/SQL-Online-Judge/backend/internal/core/service"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/db/mongo"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/logger"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/mq"
+	"go.uber.org/zap"
+)
+
+const (
+	relayInterval = time.Second
+	dequeueBlock  = 5 * time.Second
+	reclaimIdle   = time.Minute
+	maxDeliveries = 5
+)
+
+var consumerName string
+var repo *repository.MongoRepository
+
+var (
+	answerService     *service.AnswerService
+	submissionService *service.SubmissionService
+)
+
+func init() {
+	hostname, err := os.Hostname()
+	if err != nil {
+		logger.Logger.Fatal("failed to get hostname", zap.Error(err))
+	}
+	consumerName = hostname
+
+	repo = repository.NewMongoRepository(mongo.GetMongoDB())
+
+	answerService = service.NewAnswerService(repo)
+	submissionService = service.NewSubmissionService(repo)
+}
+
+// Start runs the background consumers of core.
+func Start() {
+	err := answerService.RegenerateUnreadyAnswers()
+	if err != nil {
+		logger.Logger.Error("failed to regenerate unready answers", zap.Error(err))
+	}
+
+	err = submissionService.SweepPendingSubmissions()
+	if err != nil {
+		logger.Logger.Error("failed to sweep pending submissions", zap.Error(err))
+	}
+
+	go relaySubmissionOutbox()
+	go consume(mq.QueueAnswerOutput, handleAnswerOutput)
+	go consume(mq.QueueJudgeResult, handleJudgeResult)
+	go consume(mq.QueueSimilarity, handleSimilarity)
+}
+
+// consume dequeues messages from queueName forever and acks each message
+// only after handle returns nil. Messages whose handling failed are
+// reclaimed after reclaimIdle, and dead-lettered after maxDeliveries.
+func consume(queueName string, handle func(msg *mq.Msg) error) {
+	reclaimArgs := map[string]interface{}{
+		"consumerName": consumerName,
+		"minIdle":      reclaimIdle,
+	}
+	dequeueArgs := map[string]interface{}{
+		"consumerName": consumerName,
+		"block":        dequeueBlock,
+	}
+
+	for {
+		msg, err := service.MQService.Reclaim(queueName, reclaimArgs)
+		if errors.Is(err, mq.ErrNoMessageToDequeue) {
+			msg, err = service.MQService.Dequeue(queueName, dequeueArgs)
+		}
+		if errors.Is(err, mq.ErrNoMessageToDequeue) {
+			continue
+		}
+		if err != nil {
+			time.Sleep(dequeueBlock)
+			continue
+		}
+
+		if msg.Deliveries > maxDeliveries {
+			service.MQService.DeadLetter(queueName, msg)
+			continue
+		}
+
+		err = handle(msg)
+		if err != nil {
+			logger.Logger.Error("failed to handle message", zap.String("queue", queueName), zap.String("msgID", msg.ID), zap.Error(err))
+			continue
+		}
+
+		err = msg.Ack()
+		if err != nil {
+			logger.Logger.Error("failed to ack message", zap.String("queue", queueName), zap.String("msgID", msg.ID), zap.Error(err))
+		}
+	}
+}
+
+// relaySubmissionOutbox publishes the submission outbox whenever a
+// submission is created and at every relayInterval, which picks up retries.
+func relaySubmissionOutbox() {
+	ticker := time.NewTicker(relayInterval)
+	defer ticker.Stop()
+
+	for {
+		err := submissionService.RelaySubmissionOutbox()
+		if err != nil {
+			logger.Logger.Error("failed to relay submission outbox", zap.Error(err))
+		}
+
+		select {
+		case <-ticker.C:
+		case <-service.SubmissionOutboxNotified():
+		}
+	}
+}
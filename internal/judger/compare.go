@@ -0,0 +1,326 @@
+package judger
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/SQL-Online-Judge/backend/internal/judger/engine"
+	"github.com/SQL-Online-Judge/backend/internal/model"
+)
+
+// compare returns how actual differs from expected under opts, or nil when
+// they match. A nil opts compares exactly.
+func compare(expected, actual *engine.ResultSet, opts *model.CompareOptions) *model.Diff {
+	if opts == nil {
+		opts = &model.CompareOptions{}
+	}
+
+	diff := &model.Diff{
+		ExpectedRowCount: len(expected.Rows),
+		ActualRowCount:   len(actual.Rows),
+		FirstDiffRow:     -1,
+	}
+
+	if len(expected.Columns) != len(actual.Columns) {
+		diff.Reason = model.DiffReasonColumnCount
+		diff.ExpectedColumns = expected.Columns
+		diff.ActualColumns = actual.Columns
+		return diff
+	}
+
+	columns, ok := matchColumns(expected.Columns, actual.Columns, opts.ColumnMatch)
+	if !ok {
+		diff.Reason = model.DiffReasonColumnName
+		diff.ExpectedColumns = expected.Columns
+		diff.ActualColumns = actual.Columns
+		return diff
+	}
+
+	expectedRows := normalizeRows(expected.Rows, nil, opts)
+	actualRows := normalizeRows(actual.Rows, columns, opts)
+
+	if opts.IgnoreOrder {
+		sortRows(expectedRows)
+		sortRows(actualRows)
+		missing, extra := matchRows(expectedRows, actualRows, opts.FloatTolerance)
+		firstUnmatched(diff, expectedRows, missing, actualRows, extra)
+	} else {
+		firstDifferent(diff, expectedRows, actualRows, opts.FloatTolerance)
+	}
+
+	if diff.FirstDiffRow == -1 {
+		return nil
+	}
+
+	diff.MissingRowCount, diff.ExtraRowCount = countRowDifference(expectedRows, actualRows, opts.FloatTolerance)
+	diff.Reason = model.DiffReasonRow
+	if len(expectedRows) != len(actualRows) {
+		diff.Reason = model.DiffReasonRowCount
+	}
+
+	return diff
+}
+
+func equalRow(a, b []*string, floatTolerance float64) bool {
+	return compareRow(a, b, floatTolerance) == 0
+}
+
+// firstDifferent sets the first row of diff where expected and actual
+// differ, comparing them in order.
+func firstDifferent(diff *model.Diff, expected, actual [][]*string, floatTolerance float64) {
+	for i := 0; i < len(expected) || i < len(actual); i++ {
+		if i < len(expected) && i < len(actual) && equalRow(expected[i], actual[i], floatTolerance) {
+			continue
+		}
+		diff.FirstDiffRow = i
+		if i < len(expected) {
+			diff.ExpectedRow = expected[i]
+		}
+		if i < len(actual) {
+			diff.ActualRow = actual[i]
+		}
+		return
+	}
+}
+
+// firstUnmatched sets the first row of diff that is missing or extra as
+// told by matchRows: the first expected row without a pair, along with the
+// first actual row without one.
+func firstUnmatched(diff *model.Diff, expected [][]*string, missing []bool, actual [][]*string, extra []bool) {
+	firstExpected, firstActual := firstTrue(missing), firstTrue(extra)
+	switch {
+	case firstExpected >= 0:
+		diff.FirstDiffRow = firstExpected
+	case firstActual >= 0:
+		diff.FirstDiffRow = firstActual
+	default:
+		return
+	}
+	if firstExpected >= 0 {
+		diff.ExpectedRow = expected[firstExpected]
+	}
+	if firstActual >= 0 {
+		diff.ActualRow = actual[firstActual]
+	}
+}
+
+func firstTrue(list []bool) int {
+	for i, b := range list {
+		if b {
+			return i
+		}
+	}
+	return -1
+}
+
+// countRowDifference counts the expected rows missing from actual and the
+// actual rows not expected, ignoring the row order.
+func countRowDifference(expected, actual [][]*string, floatTolerance float64) (missing, extra int) {
+	sortedExpected := append([][]*string(nil), expected...)
+	sortedActual := append([][]*string(nil), actual...)
+	sortRows(sortedExpected)
+	sortRows(sortedActual)
+
+	missingRows, extraRows := matchRows(sortedExpected, sortedActual, floatTolerance)
+	for _, m := range missingRows {
+		if m {
+			missing++
+		}
+	}
+	for _, e := range extraRows {
+		if e {
+			extra++
+		}
+	}
+	return missing, extra
+}
+
+// matchRows pairs the rows of expected with equal rows of actual under
+// floatTolerance, ignoring the row order, and tells which rows of each are
+// left without a pair. Both must be sorted by sortRows. Rows equal under a
+// tolerance may sort apart, so every expected row is paired with the first
+// free equal row among the actual rows whose first cell is within the
+// tolerance of its own, which are next to each other once sorted.
+func matchRows(expected, actual [][]*string, floatTolerance float64) (missing, extra []bool) {
+	missing = make([]bool, len(expected))
+	extra = make([]bool, len(actual))
+	for k := range extra {
+		extra[k] = true
+	}
+
+	for i, row := range expected {
+		missing[i] = true
+		k := sort.Search(len(actual), func(k int) bool {
+			return compareFirstCell(actual[k], row, floatTolerance) >= 0
+		})
+		for ; k < len(actual) && compareFirstCell(actual[k], row, floatTolerance) == 0; k++ {
+			if extra[k] && equalRow(row, actual[k], floatTolerance) {
+				missing[i], extra[k] = false, false
+				break
+			}
+		}
+	}
+
+	return missing, extra
+}
+
+// compareFirstCell compares the first cells of two rows as compareCell does;
+// rows without cells are all alike.
+func compareFirstCell(a, b []*string, floatTolerance float64) int {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) - len(b)
+	}
+	return compareCell(a[0], b[0], floatTolerance)
+}
+
+// matchColumns returns, for every expected column, the index of the actual
+// column it is compared with.
+func matchColumns(expected, actual []string, columnMatch string) ([]int, bool) {
+	columns := make([]int, len(expected))
+
+	if columnMatch == model.ColumnMatchPosition {
+		for i := range columns {
+			columns[i] = i
+		}
+		return columns, true
+	}
+
+	used := make([]bool, len(actual))
+	for i, name := range expected {
+		found := false
+		for k, actualName := range actual {
+			if used[k] || !equalColumnName(name, actualName, columnMatch) {
+				continue
+			}
+			columns[i] = k
+			used[k] = true
+			found = true
+			break
+		}
+		if !found {
+			return nil, false
+		}
+	}
+
+	return columns, true
+}
+
+func equalColumnName(a, b, columnMatch string) bool {
+	if columnMatch == model.ColumnMatchNameIgnoreCase {
+		return strings.EqualFold(a, b)
+	}
+	return a == b
+}
+
+// normalizeRows copies rows, reordering cells by columns when it is not nil
+// and applying the normalization of opts.
+func normalizeRows(rows [][]*string, columns []int, opts *model.CompareOptions) [][]*string {
+	normalized := make([][]*string, len(rows))
+	for i, row := range rows {
+		normalizedRow := make([]*string, len(row))
+		for k := range row {
+			cell := row[k]
+			if columns != nil {
+				cell = row[columns[k]]
+			}
+			normalizedRow[k] = normalizeCell(cell, opts)
+		}
+		normalized[i] = normalizedRow
+	}
+	return normalized
+}
+
+func normalizeCell(cell *string, opts *model.CompareOptions) *string {
+	if cell == nil {
+		if opts.NullAsEmpty {
+			empty := ""
+			return &empty
+		}
+		return nil
+	}
+
+	if opts.NormalizeSpace {
+		normalized := strings.Join(strings.Fields(*cell), " ")
+		return &normalized
+	}
+
+	return cell
+}
+
+func sortRows(rows [][]*string) {
+	sort.SliceStable(rows, func(i, j int) bool {
+		return lessRow(rows[i], rows[j])
+	})
+}
+
+func lessRow(a, b []*string) bool {
+	return compareRow(a, b, 0) < 0
+}
+
+// compareRow orders rows by their cells as compareCell does, then shorter
+// rows first.
+func compareRow(a, b []*string, floatTolerance float64) int {
+	for k := 0; k < len(a) && k < len(b); k++ {
+		if c := compareCell(a[k], b[k], floatTolerance); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	}
+	return 0
+}
+
+// compareCell orders NULL first, then numbers by value with NaN last, then
+// strings. Numbers within floatTolerance of each other are equal; other
+// numbers of the same value, e.g. 1 and 1.0, are ordered as strings, so
+// without a tolerance they must be written alike. Sorting is done without a
+// tolerance, which keeps the order total.
+func compareCell(a, b *string, floatTolerance float64) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+
+	fa, errA := strconv.ParseFloat(*a, 64)
+	fb, errB := strconv.ParseFloat(*b, 64)
+	switch {
+	case errA == nil && errB == nil:
+		if c := compareNumber(fa, fb, floatTolerance); c != 0 || floatTolerance > 0 {
+			return c
+		}
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	}
+
+	return strings.Compare(*a, *b)
+}
+
+func compareNumber(a, b, floatTolerance float64) int {
+	nanA, nanB := math.IsNaN(a), math.IsNaN(b)
+	switch {
+	case nanA && nanB:
+		return 0
+	case nanA:
+		return 1
+	case nanB:
+		return -1
+	case a == b, math.Abs(a-b) <= floatTolerance:
+		return 0
+	case a < b:
+		return -1
+	default:
+		return 1
+	}
+}
@@ -0,0 +1,175 @@
+package judger
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/SQL-Online-Judge/backend/internal/judger/engine"
+	"github.com/SQL-Online-Judge/backend/internal/model"
+)
+
+func cell(s string) *string {
+	return &s
+}
+
+func TestCompareCell(t *testing.T) {
+	tests := []struct {
+		name      string
+		a, b      *string
+		tolerance float64
+		want      int
+	}{
+		{name: "null", a: nil, b: nil, want: 0},
+		{name: "null first", a: nil, b: cell("1"), want: -1},
+		{name: "null before empty", a: cell(""), b: nil, want: 1},
+		{name: "numbers by value", a: cell("9"), b: cell("10"), want: -1},
+		{name: "numbers before strings", a: cell("10"), b: cell("a"), want: -1},
+		{name: "strings", a: cell("b"), b: cell("a"), want: 1},
+		{name: "same value written apart", a: cell("1.0"), b: cell("1"), want: 1},
+		{name: "same value within tolerance", a: cell("1.0"), b: cell("1"), tolerance: 0.001, want: 0},
+		{name: "within tolerance", a: cell("1.0004"), b: cell("1"), tolerance: 0.001, want: 0},
+		{name: "beyond tolerance", a: cell("1.01"), b: cell("1"), tolerance: 0.001, want: 1},
+		{name: "infinities", a: cell("Inf"), b: cell("Inf"), tolerance: 0.001, want: 0},
+		{name: "nan", a: cell("NaN"), b: cell("NaN"), want: 0},
+		{name: "nan within tolerance", a: cell("NaN"), b: cell("nan"), tolerance: 0.001, want: 0},
+		{name: "nan after numbers", a: cell("NaN"), b: cell("Inf"), want: 1},
+		{name: "nan before strings", a: cell("NaN"), b: cell("a"), want: -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := compareCell(tt.a, tt.b, tt.tolerance); got != tt.want {
+				t.Errorf("compareCell() = %d, want %d", got, tt.want)
+			}
+			if got := compareCell(tt.b, tt.a, tt.tolerance); got != -tt.want {
+				t.Errorf("compareCell() reversed = %d, want %d", got, -tt.want)
+			}
+		})
+	}
+}
+
+func TestSortRows(t *testing.T) {
+	rows := [][]*string{{cell("b")}, {cell("NaN")}, {cell("10")}, {nil}, {cell("1.0")}, {cell("9")}, {cell("1")}}
+	sortRows(rows)
+
+	var got []*string
+	for _, row := range rows {
+		got = append(got, row[0])
+	}
+	want := []*string{nil, cell("1"), cell("1.0"), cell("9"), cell("10"), cell("NaN"), cell("b")}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sorted %v, want %v", got, want)
+	}
+	if !sort.SliceIsSorted(rows, func(i, j int) bool { return lessRow(rows[i], rows[j]) }) {
+		t.Errorf("rows are not sorted by lessRow")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	resultSet := func(rows ...[]*string) *engine.ResultSet {
+		return &engine.ResultSet{Columns: []string{"a", "b"}, Rows: rows}
+	}
+	row := func(cells ...*string) []*string {
+		return cells
+	}
+
+	tests := []struct {
+		name     string
+		expected *engine.ResultSet
+		actual   *engine.ResultSet
+		opts     *model.CompareOptions
+		// want is nil when the result sets match
+		want *model.Diff
+	}{
+		{
+			name:     "equal",
+			expected: resultSet(row(cell("1"), nil)),
+			actual:   resultSet(row(cell("1"), nil)),
+		},
+		{
+			name:     "null is not empty",
+			expected: resultSet(row(cell("1"), nil)),
+			actual:   resultSet(row(cell("1"), cell(""))),
+			want: &model.Diff{
+				Reason: model.DiffReasonRow, ExpectedRowCount: 1, ActualRowCount: 1, MissingRowCount: 1, ExtraRowCount: 1,
+				FirstDiffRow: 0, ExpectedRow: row(cell("1"), nil), ActualRow: row(cell("1"), cell("")),
+			},
+		},
+		{
+			name:     "null as empty",
+			expected: resultSet(row(cell("1"), nil)),
+			actual:   resultSet(row(cell("1"), cell(""))),
+			opts:     &model.CompareOptions{NullAsEmpty: true},
+		},
+		{
+			name:     "ignore order",
+			expected: resultSet(row(cell("1"), cell("a")), row(cell("2"), cell("b"))),
+			actual:   resultSet(row(cell("2"), cell("b")), row(cell("1"), cell("a"))),
+			opts:     &model.CompareOptions{IgnoreOrder: true},
+		},
+		{
+			name:     "within tolerance",
+			expected: resultSet(row(cell("1"), cell("NaN")), row(cell("2.5"), cell("x"))),
+			actual:   resultSet(row(cell("1.0"), cell("NaN")), row(cell("2.5004"), cell("x"))),
+			opts:     &model.CompareOptions{FloatTolerance: 0.001},
+		},
+		{
+			// 1 and 1.0 differ without a tolerance, and must not be counted
+			// as matching when looking for missing and extra rows either
+			name:     "same value written apart",
+			expected: resultSet(row(cell("1"), cell("a")), row(cell("1.0"), cell("a"))),
+			actual:   resultSet(row(cell("1.0"), cell("a")), row(cell("1.0"), cell("a"))),
+			want: &model.Diff{
+				Reason: model.DiffReasonRow, ExpectedRowCount: 2, ActualRowCount: 2, MissingRowCount: 1, ExtraRowCount: 1,
+				FirstDiffRow: 0, ExpectedRow: row(cell("1"), cell("a")), ActualRow: row(cell("1.0"), cell("a")),
+			},
+		},
+		{
+			// the first cells are equal under the tolerance but sort apart,
+			// so the rows cannot be compared by position
+			name:     "ignore order within tolerance",
+			expected: resultSet(row(cell("0.3"), cell("y")), row(cell("0.30000000000000004"), cell("x"))),
+			actual:   resultSet(row(cell("0.3"), cell("x")), row(cell("0.30000000000000004"), cell("y"))),
+			opts:     &model.CompareOptions{IgnoreOrder: true, FloatTolerance: 0.001},
+		},
+		{
+			name:     "ignore order within tolerance with an extra row",
+			expected: resultSet(row(cell("0.3"), cell("y")), row(cell("0.30000000000000004"), cell("x"))),
+			actual:   resultSet(row(cell("0.3"), cell("x")), row(cell("0.30000000000000004"), cell("y")), row(cell("0.3"), cell("z"))),
+			opts:     &model.CompareOptions{IgnoreOrder: true, FloatTolerance: 0.001},
+			want: &model.Diff{
+				Reason: model.DiffReasonRowCount, ExpectedRowCount: 2, ActualRowCount: 3, ExtraRowCount: 1,
+				FirstDiffRow: 1, ActualRow: row(cell("0.3"), cell("z")),
+			},
+		},
+		{
+			name:     "in order within tolerance",
+			expected: resultSet(row(cell("0.3"), cell("y")), row(cell("0.30000000000000004"), cell("x"))),
+			actual:   resultSet(row(cell("0.3"), cell("x")), row(cell("0.30000000000000004"), cell("y"))),
+			opts:     &model.CompareOptions{FloatTolerance: 0.001},
+			want: &model.Diff{
+				Reason: model.DiffReasonRow, ExpectedRowCount: 2, ActualRowCount: 2,
+				FirstDiffRow: 0, ExpectedRow: row(cell("0.3"), cell("y")), ActualRow: row(cell("0.3"), cell("x")),
+			},
+		},
+		{
+			name:     "missing row",
+			expected: resultSet(row(cell("1"), cell("a")), row(cell("2"), cell("b"))),
+			actual:   resultSet(row(cell("1"), cell("a"))),
+			want: &model.Diff{
+				Reason: model.DiffReasonRowCount, ExpectedRowCount: 2, ActualRowCount: 1, MissingRowCount: 1,
+				FirstDiffRow: 1, ExpectedRow: row(cell("2"), cell("b")),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := compare(tt.expected, tt.actual, tt.opts)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("compare() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,8 @@
+// Package all registers every engine supported by SQL-Online-Judge.
+package all
+
+import (
+	_ "github.com/SQL-Online-Judge/backend/internal/judger/engine/mysql"
+	_ "github.com/SQL-Online-Judge/backend/internal/judger/engine/opengauss"
+	_ "github.com/SQL-Online-Judge/backend/internal/judger/engine/sqlite"
+)
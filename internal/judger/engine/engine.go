@@ -0,0 +1,125 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/SQL-Online-Judge/backend/internal/pkg/dialect"
+)
+
+var (
+	ErrEngineNotFound      = fmt.Errorf("engine not found")
+	ErrEngineRegistered    = fmt.Errorf("engine already registered")
+	ErrUnknownDialect      = fmt.Errorf("dialect is not listed in package dialect")
+	ErrTimeLimitExceeded   = fmt.Errorf("time limit exceeded")
+	ErrMemoryLimitExceeded = fmt.Errorf("memory limit exceeded")
+	ErrOutputLimitExceeded = fmt.Errorf("output limit exceeded")
+	// ErrCompileError is a statement the database failed to parse.
+	ErrCompileError = fmt.Errorf("compile error")
+)
+
+// Engine is a SQL dialect the judger can run submissions against.
+type Engine interface {
+	// Connect opens the connection pool described by dsn.
+	Connect(dsn string) error
+	// NewSandbox creates an empty database that is isolated from every
+	// other sandbox and is dropped when the sandbox is closed.
+	NewSandbox(ctx context.Context) (Sandbox, error)
+	Close() error
+}
+
+// Embedded is implemented by engines that need no database server. They are
+// enabled even when no dsn is configured.
+type Embedded interface {
+	Embedded() bool
+}
+
+func IsEmbedded(e Engine) bool {
+	embedded, ok := e.(Embedded)
+	return ok && embedded.Embedded()
+}
+
+// Limits bounds the resources of every statement run in a sandbox.
+type Limits struct {
+	Time   time.Duration
+	Memory int64 // in bytes
+	// OutputRows and OutputBytes bound what a query may return, 0 means
+	// unbounded.
+	OutputRows  int64
+	OutputBytes int64
+}
+
+// Sandbox runs statements against a single connection. The deadline of ctx
+// bounds how long each statement may run. Errors caused by the limits wrap
+// ErrTimeLimitExceeded, ErrMemoryLimitExceeded or ErrOutputLimitExceeded,
+// and statements the database fails to parse wrap ErrCompileError.
+type Sandbox interface {
+	// SetLimits applies limits to the statements run after it, as far as
+	// the dialect supports it.
+	SetLimits(ctx context.Context, limits *Limits) error
+	Exec(ctx context.Context, query string) error
+	Query(ctx context.Context, query string) (*ResultSet, error)
+	// Restrict returns a sandbox on the same database whose statements run
+	// as a throwaway user. The user can reach nothing but this sandbox and
+	// may only run the statement kinds in allowed, e.g. SELECT, or every kind
+	// when allowed is empty. Closing it drops the user. Limits set on the
+	// sandbox may not apply to it, so they must be set on it again.
+	Restrict(ctx context.Context, allowed []string) (Sandbox, error)
+	Close() error
+}
+
+var (
+	mu      sync.RWMutex
+	engines = make(map[string]Engine)
+)
+
+// Register makes an engine available under dbName, which must be registered
+// in package dialect. It is meant to be called from the init function of the
+// engine package and panics on duplicates.
+func Register(dbName string, e Engine) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if !dialect.IsSupported(dbName) {
+		panic(fmt.Errorf("%w: %s", ErrUnknownDialect, dbName))
+	}
+	if _, ok := engines[dbName]; ok {
+		panic(fmt.Errorf("%w: %s", ErrEngineRegistered, dbName))
+	}
+	engines[dbName] = e
+}
+
+func Get(dbName string) (Engine, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	e, ok := engines[dbName]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrEngineNotFound, dbName)
+	}
+	return e, nil
+}
+
+func IsSupported(dbName string) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	_, ok := engines[dbName]
+	return ok
+}
+
+// Names returns the sorted names of all registered engines.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(engines))
+	for name := range engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
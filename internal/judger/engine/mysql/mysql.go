@@ -0,0 +1,289 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SQL-Online-Judge/backend/internal/judger/engine"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/dialect"
+	"github.com/go-sql-driver/mysql"
+)
+
+const DBName = dialect.MySQL
+
+func init() {
+	engine.Register(DBName, &Engine{})
+}
+
+// Engine runs every sandbox in its own MySQL database. The user of the dsn
+// must be allowed to create users, grant them privileges and kill their
+// queries.
+type Engine struct {
+	db     *sql.DB
+	config *mysql.Config
+}
+
+func (e *Engine) Connect(dsn string) error {
+	config, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return fmt.Errorf("failed to parse mysql dsn: %w", err)
+	}
+	// PrepareSQL and submissions may contain several statements.
+	config.MultiStatements = true
+	config.DBName = ""
+
+	db, err := sql.Open("mysql", config.FormatDSN())
+	if err != nil {
+		return fmt.Errorf("failed to open mysql: %w", err)
+	}
+
+	err = db.Ping()
+	if err != nil {
+		db.Close()
+		return fmt.Errorf("failed to ping mysql: %w", err)
+	}
+
+	e.db = db
+	e.config = config
+	return nil
+}
+
+func (e *Engine) NewSandbox(ctx context.Context) (engine.Sandbox, error) {
+	return e.newSandbox(ctx, nil)
+}
+
+// newSandbox creates a sandbox database and runs the fill statements in it.
+func (e *Engine) newSandbox(ctx context.Context, fill []string) (*engine.ConnSandbox, error) {
+	name := engine.NewSandboxName()
+	setup := []string{
+		fmt.Sprintf("CREATE DATABASE `%s`", name),
+		fmt.Sprintf("USE `%s`", name),
+	}
+	setup = append(setup, fill...)
+	cleanup := []string{
+		fmt.Sprintf("DROP DATABASE IF EXISTS `%s`", name),
+	}
+
+	return engine.NewConnSandbox(ctx, e.db, e, name, setup, cleanup)
+}
+
+// template is a prepared database whose tables are copied into every
+// sandbox.
+type template struct {
+	e    *Engine
+	name string
+	// copy creates the tables in the current database and fills them
+	copy []string
+}
+
+// templateObjects counts what a template cannot copy: views, routines,
+// triggers, events and generated columns.
+const templateObjects = `SELECT
+	(SELECT COUNT(*) FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? AND TABLE_TYPE <> 'BASE TABLE') +
+	(SELECT COUNT(*) FROM information_schema.ROUTINES WHERE ROUTINE_SCHEMA = ?) +
+	(SELECT COUNT(*) FROM information_schema.TRIGGERS WHERE TRIGGER_SCHEMA = ?) +
+	(SELECT COUNT(*) FROM information_schema.EVENTS WHERE EVENT_SCHEMA = ?) +
+	(SELECT COUNT(*) FROM information_schema.COLUMNS WHERE TABLE_SCHEMA = ? AND GENERATION_EXPRESSION <> '')`
+
+// NewTemplate keeps the prepared database. A sandbox gets its tables as
+// shown by SHOW CREATE TABLE, which keeps indexes, foreign keys and
+// AUTO_INCREMENT counters, and then their rows.
+func (e *Engine) NewTemplate(ctx context.Context, prepare []string) (engine.Template, error) {
+	t := &template{e: e, name: engine.NewSandboxName()}
+	err := t.prepare(ctx, prepare)
+	if err != nil {
+		t.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *template) prepare(ctx context.Context, prepare []string) error {
+	setup := []string{
+		fmt.Sprintf("CREATE DATABASE `%s`", t.name),
+		fmt.Sprintf("USE `%s`", t.name),
+	}
+	// without cleanup statements, closing the sandbox keeps the database
+	sb, err := engine.NewConnSandbox(ctx, t.e.db, t.e, t.name, setup, nil)
+	if err != nil {
+		return err
+	}
+	err = engine.ExecAll(ctx, sb, prepare)
+	sb.Close()
+	if err != nil {
+		return fmt.Errorf("failed to run prepare sql: %w", err)
+	}
+
+	var objects int
+	err = t.e.db.QueryRowContext(ctx, templateObjects, t.name, t.name, t.name, t.name, t.name).Scan(&objects)
+	if err != nil {
+		return fmt.Errorf("failed to inspect template: %w", err)
+	}
+	if objects > 0 {
+		return fmt.Errorf("%w: it holds objects other than tables", engine.ErrCannotTemplate)
+	}
+
+	rows, err := t.e.db.QueryContext(ctx, "SELECT TABLE_NAME FROM information_schema.TABLES WHERE TABLE_SCHEMA = ? ORDER BY TABLE_NAME", t.name)
+	if err != nil {
+		return fmt.Errorf("failed to list template tables: %w", err)
+	}
+	var tables []string
+	for rows.Next() {
+		var table string
+		err = rows.Scan(&table)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to list template tables: %w", err)
+		}
+		tables = append(tables, table)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list template tables: %w", err)
+	}
+
+	// the tables are created in any order, so foreign keys are checked only
+	// once they are all filled
+	t.copy = append(t.copy, "SET FOREIGN_KEY_CHECKS = 0")
+	for _, table := range tables {
+		var shownTable, createTable string
+		err = t.e.db.QueryRowContext(ctx, fmt.Sprintf("SHOW CREATE TABLE `%s`.%s", t.name, quote(table))).Scan(&shownTable, &createTable)
+		if err != nil {
+			return fmt.Errorf("failed to show template table: %w", err)
+		}
+		t.copy = append(t.copy, createTable, fmt.Sprintf("INSERT INTO %s SELECT * FROM `%s`.%s", quote(table), t.name, quote(table)))
+	}
+	t.copy = append(t.copy, "SET FOREIGN_KEY_CHECKS = 1")
+
+	return nil
+}
+
+func (t *template) NewSandbox(ctx context.Context) (engine.Sandbox, error) {
+	return t.e.newSandbox(ctx, t.copy)
+}
+
+func (t *template) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := t.e.db.ExecContext(ctx, fmt.Sprintf("DROP DATABASE IF EXISTS `%s`", t.name))
+	if err != nil {
+		return fmt.Errorf("failed to drop template: %w", err)
+	}
+	return nil
+}
+
+// quote quotes a name chosen by the PrepareSQL.
+func quote(name string) string {
+	return "`" + strings.ReplaceAll(name, "`", "``") + "`"
+}
+
+// privileges maps the statement kinds to the privileges they need on the
+// sandbox database.
+var privileges = map[string][]string{
+	"INSERT":   {"INSERT"},
+	"UPDATE":   {"UPDATE"},
+	"DELETE":   {"DELETE"},
+	"REPLACE":  {"INSERT", "DELETE"},
+	"CREATE":   {"CREATE", "CREATE VIEW", "CREATE ROUTINE", "TRIGGER", "INDEX", "REFERENCES"},
+	"ALTER":    {"ALTER", "ALTER ROUTINE", "INDEX"},
+	"DROP":     {"DROP", "ALTER ROUTINE", "TRIGGER"},
+	"TRUNCATE": {"DROP"},
+	"RENAME":   {"ALTER", "CREATE", "DROP", "INSERT"},
+	"CALL":     {"EXECUTE"},
+}
+
+// allKinds lists the statement kinds of privileges. A problem without a
+// policy allows all of them, rather than ALL PRIVILEGES, which includes
+// e.g. EVENT and LOCK TABLES.
+var allKinds = func() []string {
+	kinds := make([]string, 0, len(privileges))
+	for kind := range privileges {
+		kinds = append(kinds, kind)
+	}
+	return kinds
+}()
+
+// CreateUserStatements grants user the privileges on the sandbox database
+// only. SELECT is always granted: UPDATE and DELETE need it to read their
+// WHERE clauses, and there is nothing in the sandbox to hide.
+func (e *Engine) CreateUserStatements(name, user, password string, allowed []string) []string {
+	if len(allowed) == 0 {
+		allowed = allKinds
+	}
+	granted := strings.Join(engine.GrantedPrivileges(allowed, privileges, "SELECT"), ", ")
+
+	return []string{
+		fmt.Sprintf("CREATE USER '%s'@'%%' IDENTIFIED BY '%s'", user, password),
+		fmt.Sprintf("GRANT %s ON `%s`.* TO '%s'@'%%'", granted, name, user),
+	}
+}
+
+func (e *Engine) DropUserStatements(name, user string) []string {
+	return []string{
+		fmt.Sprintf("DROP USER IF EXISTS '%s'@'%%'", user),
+	}
+}
+
+func (e *Engine) OpenAs(name, user, password string) (*sql.DB, error) {
+	config := e.config.Clone()
+	config.User = user
+	config.Passwd = password
+	config.DBName = name
+
+	db, err := sql.Open("mysql", config.FormatDSN())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open mysql: %w", err)
+	}
+	return db, nil
+}
+
+// LimitStatements bounds SELECT statements by max_execution_time and the
+// in-memory temporary tables by their size. Other statements run until the
+// context deadline, when they are killed by KILL QUERY.
+func (e *Engine) LimitStatements(limits *engine.Limits) []string {
+	return []string{
+		fmt.Sprintf("SET SESSION max_execution_time = %d", limits.Time.Milliseconds()),
+		fmt.Sprintf("SET SESSION max_heap_table_size = %d", limits.Memory),
+		fmt.Sprintf("SET SESSION tmp_table_size = %d", limits.Memory),
+	}
+}
+
+func (e *Engine) SessionIDQuery() string {
+	return "SELECT CONNECTION_ID()"
+}
+
+// KillStatement stops the statement only: the driver closes the connection
+// of the sandbox itself once the context is done.
+func (e *Engine) KillStatement(id int64) string {
+	return fmt.Sprintf("KILL QUERY %d", id)
+}
+
+func (e *Engine) ClassifyError(err error) error {
+	var mysqlErr *mysql.MySQLError
+	if !errors.As(err, &mysqlErr) {
+		return err
+	}
+
+	switch mysqlErr.Number {
+	case 1064, 1149: // ER_PARSE_ERROR, ER_SYNTAX_ERROR
+		return fmt.Errorf("%w: %w", engine.ErrCompileError, err)
+	case 3024: // ER_QUERY_TIMEOUT
+		return fmt.Errorf("%w: %w", engine.ErrTimeLimitExceeded, err)
+	case 1037, 1038, 1041, 1114, 4082: // out of memory, out of sort memory, out of resources, table is full, connection memory limit
+		return fmt.Errorf("%w: %w", engine.ErrMemoryLimitExceeded, err)
+	default:
+		return err
+	}
+}
+
+func (e *Engine) Close() error {
+	if e.db == nil {
+		return nil
+	}
+	return e.db.Close()
+}
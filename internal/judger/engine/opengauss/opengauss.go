@@ -0,0 +1,287 @@
+package opengauss
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/SQL-Online-Judge/backend/internal/judger/engine"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/dialect"
+	"github.com/lib/pq"
+)
+
+const DBName = dialect.OpenGauss
+
+func init() {
+	engine.Register(DBName, &Engine{})
+}
+
+// Engine runs every sandbox in its own openGauss schema. openGauss speaks
+// the PostgreSQL protocol; the server must allow md5 authentication. The
+// user of the dsn must be allowed to create roles.
+type Engine struct {
+	db  *sql.DB
+	dsn string
+}
+
+func (e *Engine) Connect(dsn string) error {
+	// OpenAs appends to the dsn, which only works with key=value pairs
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		var err error
+		dsn, err = pq.ParseURL(dsn)
+		if err != nil {
+			return fmt.Errorf("failed to parse opengauss dsn: %w", err)
+		}
+	}
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return fmt.Errorf("failed to open opengauss: %w", err)
+	}
+
+	err = db.Ping()
+	if err != nil {
+		db.Close()
+		return fmt.Errorf("failed to ping opengauss: %w", err)
+	}
+
+	e.db = db
+	e.dsn = dsn
+	return nil
+}
+
+func (e *Engine) NewSandbox(ctx context.Context) (engine.Sandbox, error) {
+	return e.newSandbox(ctx, nil)
+}
+
+// newSandbox creates a sandbox schema and runs the fill statements in it.
+func (e *Engine) newSandbox(ctx context.Context, fill []string) (*engine.ConnSandbox, error) {
+	name := engine.NewSandboxName()
+	setup := []string{
+		fmt.Sprintf("CREATE SCHEMA %s", name),
+		fmt.Sprintf("SET search_path TO %s", name),
+	}
+	setup = append(setup, fill...)
+	cleanup := []string{
+		fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", name),
+	}
+
+	return engine.NewConnSandbox(ctx, e.db, e, name, setup, cleanup)
+}
+
+// template is a prepared schema whose tables are copied into every sandbox.
+// Sandboxes are schemas of the database of the dsn, so a template is one
+// too rather than a template database: openGauss only creates databases from
+// template0, and one database per sandbox would not share the connections of
+// the dsn.
+type template struct {
+	e    *Engine
+	name string
+	// copy creates the tables in the current schema and fills them
+	copy []string
+}
+
+// templateObjects counts what a template cannot copy: anything but tables
+// and indexes, e.g. views and the sequences of serial columns, which the
+// copies would share, and foreign keys, functions and triggers.
+const templateObjects = `SELECT
+	(SELECT count(*) FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace WHERE n.nspname = $1 AND c.relkind NOT IN ('r', 'i')) +
+	(SELECT count(*) FROM pg_constraint co JOIN pg_namespace n ON n.oid = co.connamespace WHERE n.nspname = $1 AND co.contype = 'f') +
+	(SELECT count(*) FROM pg_trigger tg JOIN pg_class c ON c.oid = tg.tgrelid JOIN pg_namespace n ON n.oid = c.relnamespace WHERE n.nspname = $1 AND NOT tg.tgisinternal) +
+	(SELECT count(*) FROM pg_proc p JOIN pg_namespace n ON n.oid = p.pronamespace WHERE n.nspname = $1)`
+
+// NewTemplate keeps the prepared schema. A sandbox gets its tables with
+// CREATE TABLE ... (LIKE ... INCLUDING ALL), which keeps defaults,
+// constraints and indexes, and then their rows.
+func (e *Engine) NewTemplate(ctx context.Context, prepare []string) (engine.Template, error) {
+	t := &template{e: e, name: engine.NewSandboxName()}
+	err := t.prepare(ctx, prepare)
+	if err != nil {
+		t.Close()
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *template) prepare(ctx context.Context, prepare []string) error {
+	setup := []string{
+		fmt.Sprintf("CREATE SCHEMA %s", t.name),
+		fmt.Sprintf("SET search_path TO %s", t.name),
+	}
+	// without cleanup statements, closing the sandbox keeps the schema
+	sb, err := engine.NewConnSandbox(ctx, t.e.db, t.e, t.name, setup, nil)
+	if err != nil {
+		return err
+	}
+	err = engine.ExecAll(ctx, sb, prepare)
+	sb.Close()
+	if err != nil {
+		return fmt.Errorf("failed to run prepare sql: %w", err)
+	}
+
+	var objects int
+	err = t.e.db.QueryRowContext(ctx, templateObjects, t.name).Scan(&objects)
+	if err != nil {
+		return fmt.Errorf("failed to inspect template: %w", err)
+	}
+	if objects > 0 {
+		return fmt.Errorf("%w: it holds objects other than tables", engine.ErrCannotTemplate)
+	}
+
+	rows, err := t.e.db.QueryContext(ctx, "SELECT tablename FROM pg_tables WHERE schemaname = $1 ORDER BY tablename", t.name)
+	if err != nil {
+		return fmt.Errorf("failed to list template tables: %w", err)
+	}
+	var tables []string
+	for rows.Next() {
+		var table string
+		err = rows.Scan(&table)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to list template tables: %w", err)
+		}
+		tables = append(tables, table)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list template tables: %w", err)
+	}
+
+	for _, table := range tables {
+		table = pq.QuoteIdentifier(table)
+		t.copy = append(t.copy,
+			fmt.Sprintf("CREATE TABLE %s (LIKE %s.%s INCLUDING ALL)", table, t.name, table),
+			fmt.Sprintf("INSERT INTO %s SELECT * FROM %s.%s", table, t.name, table),
+		)
+	}
+
+	return nil
+}
+
+func (t *template) NewSandbox(ctx context.Context) (engine.Sandbox, error) {
+	return t.e.newSandbox(ctx, t.copy)
+}
+
+func (t *template) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	_, err := t.e.db.ExecContext(ctx, fmt.Sprintf("DROP SCHEMA IF EXISTS %s CASCADE", t.name))
+	if err != nil {
+		return fmt.Errorf("failed to drop template: %w", err)
+	}
+	return nil
+}
+
+// tablePrivileges maps the statement kinds to the privileges they need on
+// the tables of the sandbox schema. openGauss grants ALTER and DROP on
+// tables, so the user does not have to own them.
+var tablePrivileges = map[string][]string{
+	"INSERT":   {"INSERT"},
+	"UPDATE":   {"UPDATE"},
+	"DELETE":   {"DELETE"},
+	"MERGE":    {"INSERT", "UPDATE"},
+	"TRUNCATE": {"TRUNCATE"},
+	"CREATE":   {"INDEX", "REFERENCES"},
+	"ALTER":    {"ALTER", "INDEX"},
+	"DROP":     {"DROP"},
+}
+
+// CreateUserStatements grants user the privileges on the tables the
+// sandbox schema holds when it is called, i.e. those of the PrepareSQL. The
+// user owns the tables it creates. SELECT is always granted: UPDATE and
+// DELETE need it to read their WHERE clauses, and there is nothing in the
+// sandbox to hide.
+func (e *Engine) CreateUserStatements(name, user, password string, allowed []string) []string {
+	granted := "ALL PRIVILEGES"
+	if list := engine.GrantedPrivileges(allowed, tablePrivileges, "SELECT"); list != nil {
+		granted = strings.Join(list, ", ")
+	}
+
+	stmts := []string{
+		// unlike CREATE USER, CREATE ROLE does not create a schema of the
+		// same name
+		fmt.Sprintf("CREATE ROLE %s LOGIN PASSWORD '%s'", user, password),
+		fmt.Sprintf("GRANT USAGE ON SCHEMA %s TO %s", name, user),
+		fmt.Sprintf("GRANT %s ON ALL TABLES IN SCHEMA %s TO %s", granted, name, user),
+		// serial columns draw from sequences
+		fmt.Sprintf("GRANT USAGE, SELECT ON ALL SEQUENCES IN SCHEMA %s TO %s", name, user),
+	}
+	if len(allowed) == 0 || containsFold(allowed, "CREATE") {
+		stmts = append(stmts, fmt.Sprintf("GRANT CREATE ON SCHEMA %s TO %s", name, user))
+	}
+	return stmts
+}
+
+// DropUserStatements drops what user created in the schema before the role,
+// which cannot be dropped while it owns anything.
+func (e *Engine) DropUserStatements(name, user string) []string {
+	return []string{
+		fmt.Sprintf("DROP OWNED BY %s CASCADE", user),
+		fmt.Sprintf("DROP ROLE IF EXISTS %s", user),
+	}
+}
+
+func (e *Engine) OpenAs(name, user, password string) (*sql.DB, error) {
+	// later pairs override earlier ones, and unknown keys such as
+	// search_path are set on the session
+	dsn := fmt.Sprintf("%s user=%s password=%s search_path=%s", e.dsn, quoteValue(user), quoteValue(password), quoteValue(name))
+
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open opengauss: %w", err)
+	}
+	return db, nil
+}
+
+// quoteValue quotes a value of a key=value dsn, so that it cannot end early
+// or add pairs of its own.
+func quoteValue(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, `'`, `\'`)
+	return "'" + value + "'"
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Engine) LimitStatements(limits *engine.Limits) []string {
+	return []string{
+		fmt.Sprintf("SET statement_timeout = %d", limits.Time.Milliseconds()),
+		fmt.Sprintf("SET query_max_mem = '%dkB'", limits.Memory/1024),
+	}
+}
+
+func (e *Engine) ClassifyError(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	switch pqErr.Code {
+	case "42601": // syntax_error
+		return fmt.Errorf("%w: %w", engine.ErrCompileError, err)
+	case "57014": // query_canceled
+		return fmt.Errorf("%w: %w", engine.ErrTimeLimitExceeded, err)
+	case "53000", "53200": // insufficient_resources, out_of_memory
+		return fmt.Errorf("%w: %w", engine.ErrMemoryLimitExceeded, err)
+	default:
+		return err
+	}
+}
+
+func (e *Engine) Close() error {
+	if e.db == nil {
+		return nil
+	}
+	return e.db.Close()
+}
@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+)
+
+type ResultSet struct {
+	Columns []string    `json:"columns"`
+	Rows    [][]*string `json:"rows"`
+}
+
+func (rs *ResultSet) ToJSON() (string, error) {
+	j, err := json.Marshal(rs)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal ResultSet: %w", err)
+	}
+	return string(j), nil
+}
+
+func (rs *ResultSet) FromJSON(j string) error {
+	err := json.Unmarshal([]byte(j), rs)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal ResultSet: %w", err)
+	}
+	return nil
+}
+
+// ScanResultSet reads every result set returned by rows and keeps the last
+// one that has columns, so that "INSERT ...; SELECT ..." yields the SELECT.
+// It stops with ErrOutputLimitExceeded as soon as a result set has more than
+// maxRows rows or maxBytes bytes of values; 0 means no limit.
+func ScanResultSet(rows *sql.Rows, maxRows, maxBytes int64) (*ResultSet, error) {
+	rs := &ResultSet{Columns: []string{}, Rows: [][]*string{}}
+
+	for {
+		columns, err := rows.Columns()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get columns: %w", err)
+		}
+
+		if len(columns) > 0 {
+			rs = &ResultSet{Columns: columns, Rows: [][]*string{}}
+		}
+
+		var bytes int64
+
+		for rows.Next() {
+			values := make([]sql.NullString, len(columns))
+			dest := make([]interface{}, len(columns))
+			for i := range values {
+				dest[i] = &values[i]
+			}
+
+			err := rows.Scan(dest...)
+			if err != nil {
+				return nil, fmt.Errorf("failed to scan row: %w", err)
+			}
+
+			row := make([]*string, len(columns))
+			for i, value := range values {
+				if value.Valid {
+					v := value.String
+					row[i] = &v
+					bytes += int64(len(v))
+				}
+			}
+			rs.Rows = append(rs.Rows, row)
+
+			if maxRows > 0 && int64(len(rs.Rows)) > maxRows {
+				return nil, fmt.Errorf("%w: more than %d rows", ErrOutputLimitExceeded, maxRows)
+			}
+			if maxBytes > 0 && bytes > maxBytes {
+				return nil, fmt.Errorf("%w: more than %d bytes", ErrOutputLimitExceeded, maxBytes)
+			}
+		}
+
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read rows: %w", err)
+		}
+
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read result sets: %w", err)
+	}
+
+	return rs, nil
+}
@@ -0,0 +1,303 @@
+package engine
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/SQL-Online-Judge/backend/internal/pkg/id"
+)
+
+// NewSandboxName returns a unique name for a sandbox database or schema.
+func NewSandboxName() string {
+	return fmt.Sprintf("sqloj_%d", id.NewID())
+}
+
+// Dialect describes what a ConnSandbox needs to know about the database
+// behind it.
+type Dialect interface {
+	// LimitStatements returns the statements applying limits to a session.
+	LimitStatements(limits *Limits) []string
+	// ClassifyError wraps err with ErrTimeLimitExceeded,
+	// ErrMemoryLimitExceeded or ErrCompileError when the database reports
+	// such a failure.
+	ClassifyError(err error) error
+}
+
+// UserDialect is implemented by the dialects of database servers, whose
+// sandboxes can be restricted to a throwaway user.
+type UserDialect interface {
+	Dialect
+	// CreateUserStatements returns the statements creating user, identified
+	// by password, that can reach nothing but the sandbox name and may only
+	// run the statement kinds in allowed, every kind when empty.
+	CreateUserStatements(name, user, password string, allowed []string) []string
+	// DropUserStatements returns the statements dropping user and what it
+	// was granted.
+	DropUserStatements(name, user string) []string
+	// OpenAs opens a connection pool logged in as user, with the sandbox
+	// name as the current database or schema.
+	OpenAs(name, user, password string) (*sql.DB, error)
+}
+
+// KillDialect is implemented by the dialects of database servers that keep
+// running a statement after its client gave up on it. When the context of a
+// statement is done, the statement is killed from another connection.
+type KillDialect interface {
+	Dialect
+	// SessionIDQuery returns the query returning the id of the session.
+	SessionIDQuery() string
+	// KillStatement returns the statement stopping what the session id
+	// runs without closing the session.
+	KillStatement(id int64) string
+}
+
+// GrantedPrivileges returns the sorted privileges needed to run the
+// statement kinds in allowed, as mapped by privileges, together with base.
+// Unknown kinds need no privilege. It returns nil when allowed is empty,
+// which allows every kind.
+func GrantedPrivileges(allowed []string, privileges map[string][]string, base ...string) []string {
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	granted := make(map[string]bool)
+	for _, privilege := range base {
+		granted[privilege] = true
+	}
+	for _, kind := range allowed {
+		for _, privilege := range privileges[strings.ToUpper(kind)] {
+			granted[privilege] = true
+		}
+	}
+
+	list := make([]string, 0, len(granted))
+	for privilege := range granted {
+		list = append(list, privilege)
+	}
+	sort.Strings(list)
+	return list
+}
+
+// newPassword returns a random password that satisfies the complexity rules
+// of both MySQL and openGauss.
+func newPassword() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate password: %w", err)
+	}
+	return "Sq1_" + hex.EncodeToString(b), nil
+}
+
+// ConnSandbox is a Sandbox bound to a single database/sql connection. The
+// setup statements run on that connection when it is created. When it is
+// closed the connection is discarded instead of returned to the pool, so no
+// session state leaks into the next sandbox, and the cleanup statements run
+// on another connection of the pool.
+type ConnSandbox struct {
+	name string
+	db   *sql.DB
+	// connDB is the pool conn comes from when it is not db, it is closed
+	// with the sandbox
+	connDB  *sql.DB
+	conn    *sql.Conn
+	dialect Dialect
+	cleanup []string
+	limits  Limits
+	// sessionID is the id of the session of conn when dialect is a
+	// KillDialect
+	sessionID int64
+}
+
+// NewConnSandbox returns a sandbox on a connection of db. name is the
+// database or schema of the sandbox, it is only used by a UserDialect.
+func NewConnSandbox(ctx context.Context, db *sql.DB, dialect Dialect, name string, setup, cleanup []string) (*ConnSandbox, error) {
+	return newConnSandbox(ctx, db, db, dialect, name, setup, cleanup)
+}
+
+func newConnSandbox(ctx context.Context, db, connDB *sql.DB, dialect Dialect, name string, setup, cleanup []string) (*ConnSandbox, error) {
+	conn, err := connDB.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get connection: %w", err)
+	}
+
+	sb := &ConnSandbox{
+		name:    name,
+		db:      db,
+		conn:    conn,
+		dialect: dialect,
+		cleanup: cleanup,
+	}
+	if connDB != db {
+		sb.connDB = connDB
+	}
+
+	for _, stmt := range setup {
+		_, err := conn.ExecContext(ctx, stmt)
+		if err != nil {
+			sb.Close()
+			return nil, fmt.Errorf("failed to set up sandbox: %w", err)
+		}
+	}
+
+	if dialect, ok := dialect.(KillDialect); ok {
+		err := conn.QueryRowContext(ctx, dialect.SessionIDQuery()).Scan(&sb.sessionID)
+		if err != nil {
+			sb.Close()
+			return nil, fmt.Errorf("failed to get session id: %w", err)
+		}
+	}
+
+	return sb, nil
+}
+
+// Restrict creates a throwaway user as described by UserDialect and returns
+// a sandbox running as that user on the same database or schema. The sandbox
+// of any other dialect is private to its connection already, so it is
+// returned as is, and closing the returned sandbox leaves it open.
+func (sb *ConnSandbox) Restrict(ctx context.Context, allowed []string) (Sandbox, error) {
+	dialect, ok := sb.dialect.(UserDialect)
+	if !ok {
+		return sandboxView{sb}, nil
+	}
+
+	user := NewSandboxName()
+	password, err := newPassword()
+	if err != nil {
+		return nil, err
+	}
+
+	cleanup := dialect.DropUserStatements(sb.name, user)
+	for _, stmt := range dialect.CreateUserStatements(sb.name, user, password, allowed) {
+		_, err := sb.db.ExecContext(ctx, stmt)
+		if err != nil {
+			dropUser(sb.db, cleanup)
+			return nil, fmt.Errorf("failed to create sandbox user: %w", err)
+		}
+	}
+
+	userDB, err := dialect.OpenAs(sb.name, user, password)
+	if err != nil {
+		dropUser(sb.db, cleanup)
+		return nil, fmt.Errorf("failed to open sandbox as user: %w", err)
+	}
+	userDB.SetMaxOpenConns(1)
+
+	restricted, err := newConnSandbox(ctx, sb.db, userDB, sb.dialect, sb.name, nil, cleanup)
+	if err != nil {
+		userDB.Close()
+		dropUser(sb.db, cleanup)
+		return nil, err
+	}
+	return restricted, nil
+}
+
+func dropUser(db *sql.DB, cleanup []string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	for _, stmt := range cleanup {
+		db.ExecContext(ctx, stmt)
+	}
+}
+
+// sandboxView shares the sandbox it embeds; closing it does nothing.
+type sandboxView struct {
+	Sandbox
+}
+
+func (sv sandboxView) Close() error {
+	return nil
+}
+
+// Raw runs f on the driver connection of the sandbox, see sql.Conn.Raw.
+func (sb *ConnSandbox) Raw(f func(driverConn interface{}) error) error {
+	return sb.conn.Raw(f)
+}
+
+func (sb *ConnSandbox) SetLimits(ctx context.Context, limits *Limits) error {
+	sb.limits = *limits
+	for _, stmt := range sb.dialect.LimitStatements(limits) {
+		_, err := sb.conn.ExecContext(ctx, stmt)
+		if err != nil {
+			return fmt.Errorf("failed to set limits: %w", err)
+		}
+	}
+	return nil
+}
+
+// killOnDone kills the statement running in the sandbox once ctx is done,
+// until the returned function is called.
+func (sb *ConnSandbox) killOnDone(ctx context.Context) func() bool {
+	dialect, ok := sb.dialect.(KillDialect)
+	if !ok {
+		return func() bool { return true }
+	}
+
+	return context.AfterFunc(ctx, func() {
+		killCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		sb.db.ExecContext(killCtx, dialect.KillStatement(sb.sessionID))
+	})
+}
+
+func (sb *ConnSandbox) Exec(ctx context.Context, query string) error {
+	defer sb.killOnDone(ctx)()
+
+	_, err := sb.conn.ExecContext(ctx, query)
+	if err != nil {
+		return fmt.Errorf("failed to exec: %w", sb.dialect.ClassifyError(err))
+	}
+	return nil
+}
+
+func (sb *ConnSandbox) Query(ctx context.Context, query string) (*ResultSet, error) {
+	defer sb.killOnDone(ctx)()
+
+	rows, err := sb.conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query: %w", sb.dialect.ClassifyError(err))
+	}
+	defer rows.Close()
+
+	rs, err := ScanResultSet(rows, sb.limits.OutputRows, sb.limits.OutputBytes)
+	if errors.Is(err, ErrOutputLimitExceeded) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, sb.dialect.ClassifyError(err)
+	}
+	return rs, nil
+}
+
+func (sb *ConnSandbox) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// returning driver.ErrBadConn makes database/sql close the connection
+	// instead of putting it back into the pool
+	sb.conn.Raw(func(driverConn interface{}) error {
+		return driver.ErrBadConn
+	})
+	if sb.connDB != nil {
+		sb.connDB.Close()
+	}
+
+	var closeErr error
+	for _, stmt := range sb.cleanup {
+		_, err := sb.db.ExecContext(ctx, stmt)
+		if err != nil && closeErr == nil {
+			closeErr = fmt.Errorf("failed to clean up sandbox: %w", err)
+		}
+	}
+
+	return closeErr
+}
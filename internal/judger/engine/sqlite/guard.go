@@ -0,0 +1,121 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"unsafe"
+
+	"modernc.org/libc"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// A guard keeps the SQL of a sandbox inside its own database. SQLite has no
+// users to restrict, so the guard works on the connection itself: no
+// database can be attached, which would reach the file system or the shared
+// cache of another connection, and no PRAGMA runs while denyPragma is set,
+// which would lift the limits of the sandbox.
+type guard struct {
+	id         uintptr
+	denyPragma atomic.Bool
+}
+
+var (
+	guardsMu  sync.Mutex
+	guards    = make(map[uintptr]*guard)
+	lastGuard uintptr
+)
+
+// installGuard limits the connection driverConn of modernc.org/sqlite to no
+// attached database and sets the authorizer of the returned guard on it.
+func installGuard(driverConn interface{}) (*guard, error) {
+	db, tls, err := connHandle(driverConn)
+	if err != nil {
+		return nil, err
+	}
+
+	sqlite3.Xsqlite3_limit(tls, db, sqlite3.SQLITE_LIMIT_ATTACHED, 0)
+
+	guardsMu.Lock()
+	lastGuard++
+	g := &guard{id: lastGuard}
+	guards[g.id] = g
+	guardsMu.Unlock()
+
+	rc := sqlite3.Xsqlite3_set_authorizer(tls, db, cFuncPointer(authorize), g.id)
+	if rc != sqlite3.SQLITE_OK {
+		g.remove()
+		return nil, fmt.Errorf("failed to set sqlite authorizer: error code %d", rc)
+	}
+	return g, nil
+}
+
+// connHandle returns the handle of the connection driverConn and the
+// thread state to call the C API of SQLite with. The driver has no API for
+// an authorizer and does not export them, so they are read from its
+// unexported fields by reflection. This depends on the version of the
+// driver pinned in go.mod, and checkGuard makes sure it still works.
+func connHandle(driverConn interface{}) (uintptr, *libc.TLS, error) {
+	v := reflect.ValueOf(driverConn)
+	if v.Kind() != reflect.Pointer || v.Elem().Kind() != reflect.Struct {
+		return 0, nil, fmt.Errorf("unexpected sqlite connection %T", driverConn)
+	}
+	dbField, tlsField := v.Elem().FieldByName("db"), v.Elem().FieldByName("tls")
+	if dbField.Kind() != reflect.Uintptr || !tlsField.IsValid() || tlsField.Type() != reflect.TypeOf((*libc.TLS)(nil)) {
+		return 0, nil, fmt.Errorf("unexpected sqlite connection %T: no db and tls fields", driverConn)
+	}
+	return uintptr(dbField.Uint()), (*libc.TLS)(tlsField.UnsafePointer()), nil
+}
+
+// checkGuard guards a sandbox and makes sure that ATTACH and, once
+// restricted, PRAGMA are denied, so that a driver whose internals changed is
+// caught before any submission runs.
+func checkGuard() error {
+	ctx := context.Background()
+	sb, err := (&Engine{}).newSandbox(ctx, ":memory:")
+	if err != nil {
+		return err
+	}
+	defer sb.Close()
+
+	if sb.Exec(ctx, "ATTACH DATABASE ':memory:' AS x") == nil {
+		return fmt.Errorf("sqlite guard does not deny ATTACH")
+	}
+	if (restricted{sb}).Exec(ctx, "PRAGMA max_page_count = 1") == nil {
+		return fmt.Errorf("sqlite guard does not deny PRAGMA")
+	}
+	return nil
+}
+
+// remove forgets the guard, the authorizer then denies every PRAGMA.
+func (g *guard) remove() {
+	guardsMu.Lock()
+	delete(guards, g.id)
+	guardsMu.Unlock()
+}
+
+// authorize is the authorizer of a guarded connection, pArg is the id of
+// its guard.
+func authorize(tls *libc.TLS, pArg uintptr, action int32, arg1, arg2, dbName, trigger uintptr) int32 {
+	switch action {
+	case sqlite3.SQLITE_ATTACH, sqlite3.SQLITE_DETACH:
+		return sqlite3.SQLITE_DENY
+	case sqlite3.SQLITE_PRAGMA:
+		guardsMu.Lock()
+		g, ok := guards[pArg]
+		guardsMu.Unlock()
+		if !ok || g.denyPragma.Load() {
+			return sqlite3.SQLITE_DENY
+		}
+	}
+	return sqlite3.SQLITE_OK
+}
+
+// cFuncPointer returns the pointer to f that the C code translated by
+// modernc.org calls, as the driver does for its own callbacks. f must be a
+// top-level function.
+func cFuncPointer[T any](f T) uintptr {
+	return *(*uintptr)(unsafe.Pointer(&struct{ f T }{f}))
+}
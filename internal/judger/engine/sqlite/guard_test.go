@@ -0,0 +1,40 @@
+package sqlite
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+)
+
+// TestConnHandle fails when modernc.org/sqlite no longer has the fields the
+// guard reads, e.g. after an upgrade, instead of leaving the engine disabled.
+func TestConnHandle(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		t.Fatalf("failed to open sqlite: %v", err)
+	}
+	defer db.Close()
+
+	conn, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatalf("failed to get connection: %v", err)
+	}
+	defer conn.Close()
+
+	err = conn.Raw(func(driverConn interface{}) error {
+		handle, tls, err := connHandle(driverConn)
+		if err == nil && (handle == 0 || tls == nil) {
+			t.Errorf("connHandle() = %v, %v, want a connection handle and its thread state", handle, tls)
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatalf("the sqlite driver is not supported by the guard: %v", err)
+	}
+}
+
+func TestCheckGuard(t *testing.T) {
+	if err := checkGuard(); err != nil {
+		t.Fatalf("checkGuard() = %v, the sqlite engine is disabled", err)
+	}
+}
@@ -0,0 +1,243 @@
+package sqlite
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/SQL-Online-Judge/backend/internal/judger/engine"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/dialect"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/logger"
+	"go.uber.org/zap"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+const (
+	DBName   = dialect.SQLite
+	pageSize = 4096
+)
+
+// init registers the engine only if the guard works with the driver:
+// without it, sandboxes could reach the file system.
+func init() {
+	err := checkGuard()
+	if err != nil {
+		logger.Logger.Error("sqlite engine is disabled", zap.Error(err))
+		return
+	}
+	engine.Register(DBName, &Engine{})
+}
+
+// Engine runs every sandbox in a private in-memory SQLite database, so it
+// needs no database server at all. SQLite has no users: a restricted sandbox
+// is the sandbox itself, with the PRAGMA statements denied by its guard.
+type Engine struct{}
+
+// Connect accepts an empty dsn; there is nothing to connect to.
+func (e *Engine) Connect(dsn string) error {
+	return nil
+}
+
+func (e *Engine) Embedded() bool {
+	return true
+}
+
+func (e *Engine) NewSandbox(ctx context.Context) (engine.Sandbox, error) {
+	return e.newSandbox(ctx, ":memory:")
+}
+
+// newSandbox opens the database dsn in a pool limited to the one connection
+// held by the sandbox: every connection to ":memory:" is a new database.
+func (e *Engine) newSandbox(ctx context.Context, dsn string) (*sandbox, error) {
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+
+	setup := []string{
+		fmt.Sprintf("PRAGMA page_size = %d", pageSize),
+		"PRAGMA foreign_keys = ON",
+	}
+	connSandbox, err := engine.NewConnSandbox(ctx, db, e, "", setup, nil)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	var g *guard
+	err = connSandbox.Raw(func(driverConn interface{}) error {
+		g, err = installGuard(driverConn)
+		return err
+	})
+	if err != nil {
+		connSandbox.Close()
+		db.Close()
+		return nil, fmt.Errorf("failed to guard sqlite: %w", err)
+	}
+
+	return &sandbox{ConnSandbox: connSandbox, db: db, guard: g}, nil
+}
+
+// restorer is implemented by the connections of modernc.org/sqlite.
+type restorer interface {
+	NewRestore(srcURI string) (*sqlite.Backup, error)
+}
+
+// template is an in-memory database shared by name, which lives as long as
+// the sandbox it is prepared in is open. The name is random, so only the
+// judger can open it; sandboxes cannot attach databases anyway.
+type template struct {
+	e   *Engine
+	sb  *sandbox
+	uri string
+}
+
+// NewTemplate keeps the prepared database, a sandbox starts from an online
+// backup of it.
+func (e *Engine) NewTemplate(ctx context.Context, prepare []string) (engine.Template, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate template name: %w", err)
+	}
+	uri := fmt.Sprintf("file:sqloj_%s?mode=memory&cache=shared", hex.EncodeToString(b))
+
+	sb, err := e.newSandbox(ctx, uri)
+	if err != nil {
+		return nil, err
+	}
+
+	err = engine.ExecAll(ctx, sb, prepare)
+	if err != nil {
+		sb.Close()
+		return nil, fmt.Errorf("failed to run prepare sql: %w", err)
+	}
+
+	return &template{e: e, sb: sb, uri: uri}, nil
+}
+
+func (t *template) NewSandbox(ctx context.Context) (engine.Sandbox, error) {
+	sb, err := t.e.newSandbox(ctx, ":memory:")
+	if err != nil {
+		return nil, err
+	}
+
+	err = sb.Raw(func(driverConn interface{}) error {
+		r, ok := driverConn.(restorer)
+		if !ok {
+			return fmt.Errorf("%w: the driver cannot restore", engine.ErrCannotTemplate)
+		}
+
+		backup, err := r.NewRestore(t.uri)
+		if err != nil {
+			return err
+		}
+		_, err = backup.Step(-1)
+		finishErr := backup.Finish()
+		if err != nil {
+			return err
+		}
+		return finishErr
+	})
+	if err != nil {
+		sb.Close()
+		return nil, fmt.Errorf("failed to restore template: %w", err)
+	}
+
+	return sb, nil
+}
+
+func (t *template) Close() error {
+	return t.sb.Close()
+}
+
+// LimitStatements caps the size of the in-memory database. SQLite has no
+// statement timeout; statements are interrupted by the context deadline.
+func (e *Engine) LimitStatements(limits *engine.Limits) []string {
+	return []string{
+		fmt.Sprintf("PRAGMA max_page_count = %d", limits.Memory/pageSize),
+	}
+}
+
+func (e *Engine) ClassifyError(err error) error {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) {
+		return err
+	}
+
+	switch sqliteErr.Code() & 0xff {
+	case sqlite3.SQLITE_ERROR:
+		// SQLite reports parse failures with the generic error code
+		if isSyntaxError(sqliteErr.Error()) {
+			return fmt.Errorf("%w: %w", engine.ErrCompileError, err)
+		}
+		return err
+	case sqlite3.SQLITE_INTERRUPT:
+		return fmt.Errorf("%w: %w", engine.ErrTimeLimitExceeded, err)
+	case sqlite3.SQLITE_NOMEM, sqlite3.SQLITE_FULL:
+		return fmt.Errorf("%w: %w", engine.ErrMemoryLimitExceeded, err)
+	default:
+		return err
+	}
+}
+
+func isSyntaxError(msg string) bool {
+	return strings.Contains(msg, "syntax error") || strings.Contains(msg, "incomplete input") || strings.Contains(msg, "unrecognized token")
+}
+
+func (e *Engine) Close() error {
+	return nil
+}
+
+type sandbox struct {
+	*engine.ConnSandbox
+	db    *sql.DB
+	guard *guard
+}
+
+// Restrict returns the sandbox running with PRAGMA denied, so the limits set
+// on it stay. Closing the returned sandbox leaves it open.
+func (sb *sandbox) Restrict(ctx context.Context, allowed []string) (engine.Sandbox, error) {
+	return restricted{sb}, nil
+}
+
+func (sb *sandbox) Close() error {
+	sb.guard.remove()
+	err := sb.ConnSandbox.Close()
+	if err != nil {
+		sb.db.Close()
+		return err
+	}
+
+	err = sb.db.Close()
+	if err != nil {
+		return fmt.Errorf("failed to close sqlite: %w", err)
+	}
+	return nil
+}
+
+type restricted struct {
+	*sandbox
+}
+
+func (r restricted) Exec(ctx context.Context, query string) error {
+	r.guard.denyPragma.Store(true)
+	defer r.guard.denyPragma.Store(false)
+	return r.sandbox.Exec(ctx, query)
+}
+
+func (r restricted) Query(ctx context.Context, query string) (*engine.ResultSet, error) {
+	r.guard.denyPragma.Store(true)
+	defer r.guard.denyPragma.Store(false)
+	return r.sandbox.Query(ctx, query)
+}
+
+func (r restricted) Close() error {
+	return nil
+}
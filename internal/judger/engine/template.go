@@ -0,0 +1,38 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+)
+
+// ErrCannotTemplate is returned by NewTemplate when the prepared database
+// holds what cannot be copied into a sandbox.
+var ErrCannotTemplate = fmt.Errorf("cannot be used as a template")
+
+// Template is a database prepared once and copied into new sandboxes, which
+// is faster than running the same PrepareSQL in each of them.
+type Template interface {
+	// NewSandbox returns a new sandbox holding a copy of the template. The
+	// session state left by the prepare statements, e.g. variables, is not
+	// copied.
+	NewSandbox(ctx context.Context) (Sandbox, error)
+	Close() error
+}
+
+// Templater is implemented by engines that can keep templates.
+type Templater interface {
+	// NewTemplate runs the prepare statements in a new database and keeps it
+	// as a template.
+	NewTemplate(ctx context.Context, prepare []string) (Template, error)
+}
+
+// ExecAll runs the statements in sb one by one.
+func ExecAll(ctx context.Context, sb Sandbox, stmts []string) error {
+	for _, stmt := range stmts {
+		err := sb.Exec(ctx, stmt)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package judger
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/SQL-Online-Judge/backend/internal/judger/engine"
+	"github.com/SQL-Online-Judge/backend/internal/model"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/logger"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/mq"
+	"go.uber.org/zap"
+)
+
+// generateTimeout bounds everything run to generate a dataset, PrepareSQL
+// included.
+const generateTimeout = 5 * time.Minute
+
+// generateLimits bound the answer like a submission to a problem with the
+// highest limits allowed, since the problem is not part of the request.
+var generateLimits = &engine.Limits{
+	Time:   60 * time.Second,
+	Memory: 4096 << 20,
+}
+
+func (j *Judger) handleAnswerGenerate(msg *mq.Msg) {
+	var req model.AnswerGenerateRequest
+	err := req.FromJSON(msg.Data)
+	if err != nil || req.Answer == nil {
+		logger.Logger.Error("drop invalid answer generate request", zap.String("msgID", msg.ID), zap.Error(err))
+		ack(msg)
+		return
+	}
+
+	resp := &model.AnswerGenerateResponse{
+		AnswerID: req.AnswerID,
+		Revision: req.Revision,
+	}
+
+	outputs, err := j.generate(req.Answer)
+	if err != nil {
+		logger.Logger.Info("failed to generate answer output", zap.String("answerID", req.AnswerID), zap.Error(err))
+		resp.Error = err.Error()
+	} else {
+		resp.AnswerOutput = outputs[0]
+		resp.AnswerOutputs = outputs
+	}
+
+	err = j.publishAnswerOutput(resp)
+	if err != nil {
+		logger.Logger.Error("failed to publish answer output", zap.String("answerID", req.AnswerID), zap.Error(err))
+		return
+	}
+
+	ack(msg)
+}
+
+// giveUpAnswerGenerate reports an answer whose generate request keeps
+// failing as not generatable.
+func (j *Judger) giveUpAnswerGenerate(msg *mq.Msg) error {
+	var req model.AnswerGenerateRequest
+	err := req.FromJSON(msg.Data)
+	if err != nil {
+		return nil
+	}
+
+	return j.publishAnswerOutput(&model.AnswerGenerateResponse{
+		AnswerID: req.AnswerID,
+		Revision: req.Revision,
+		Error:    "answer generate request failed too many times",
+	})
+}
+
+func (j *Judger) publishAnswerOutput(resp *model.AnswerGenerateResponse) error {
+	respJSON, err := resp.ToJSON()
+	if err != nil {
+		return fmt.Errorf("failed to marshal answer generate response: %w", err)
+	}
+
+	err = j.ms.Enqueue(mq.QueueAnswerOutput, respJSON)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue answer generate response: %w", err)
+	}
+
+	return nil
+}
+
+// generate returns the canonical output of the answer on every dataset.
+func (j *Judger) generate(answer *model.JudgeAnswer) ([]string, error) {
+	datasets := answer.GetDatasets()
+	outputs := make([]string, 0, len(datasets))
+	for i, dataset := range datasets {
+		output, err := j.generateDataset(answer, dataset)
+		if err != nil {
+			if len(answer.Datasets) > 1 {
+				return nil, fmt.Errorf("dataset %d: %w", i+1, err)
+			}
+			return nil, err
+		}
+		outputs = append(outputs, output)
+	}
+
+	return outputs, nil
+}
+
+// generateDataset runs the PrepareSQL and AnswerSQL in a fresh sandbox and
+// returns the canonical output. When JudgeSQL is set, the output is the
+// result of JudgeSQL run after AnswerSQL, i.e. the state left behind by the
+// answer.
+func (j *Judger) generateDataset(answer *model.JudgeAnswer, dataset *model.Dataset) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), generateTimeout)
+	defer cancel()
+
+	sb, err := j.newSandbox(ctx, answer, dataset)
+	if err != nil {
+		return "", err
+	}
+	defer closeSandbox(sb)
+
+	err = sb.SetLimits(ctx, generateLimits)
+	if err != nil {
+		return "", fmt.Errorf("failed to set limits: %w", err)
+	}
+
+	// the grace period lets the database report its own timeout first
+	runCtx, cancelRun := context.WithTimeout(ctx, generateLimits.Time+timeLimitGrace)
+	defer cancelRun()
+
+	var rs *engine.ResultSet
+	if answer.JudgeSQL == "" {
+		rs, err = sb.Query(runCtx, answer.AnswerSQL)
+		if err != nil {
+			return "", fmt.Errorf("failed to run answer sql: %w", err)
+		}
+	} else {
+		err = sb.Exec(runCtx, answer.AnswerSQL)
+		if err != nil {
+			return "", fmt.Errorf("failed to run answer sql: %w", err)
+		}
+
+		rs, err = sb.Query(ctx, answer.JudgeSQL)
+		if err != nil {
+			return "", fmt.Errorf("failed to run judge sql: %w", err)
+		}
+	}
+
+	return rs.ToJSON()
+}
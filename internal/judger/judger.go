@@ -0,0 +1,611 @@
+package judger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/SQL-Online-Judge/backend/internal/judger/engine"
+	"github.com/SQL-Online-Judge/backend/internal/model"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/heartbeat"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/logger"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/mq"
+	"go.uber.org/zap"
+)
+
+const (
+	dequeueBlock   = 5 * time.Second
+	timeLimitGrace = time.Second
+	// a message not acked for reclaimIdle is taken to belong to a crashed
+	// judger and is delivered again; a message being handled is touched
+	// every touchInterval, so it never stays idle that long
+	reclaimIdle   = 5 * time.Minute
+	touchInterval = time.Minute
+	// a message delivered more than maxDeliveries times is dead-lettered
+	maxDeliveries = 3
+	// the judger is listed as alive for heartbeatTTL after each heartbeat
+	heartbeatInterval = 10 * time.Second
+	heartbeatTTL      = 30 * time.Second
+)
+
+var ErrEngineNotConnected = fmt.Errorf("engine is not connected")
+
+// Version is reported in the heartbeat, set it at build time with
+// -ldflags "-X github.com/SQL-Online-Judge/backend/internal/judger.Version=...".
+var Version = "dev"
+
+type Judger struct {
+	name     string
+	workers  int
+	engines  map[string]engine.Engine
+	ms       *mq.Service
+	registry *heartbeat.Registry
+	// templates hold the prepared databases of the answers judged recently
+	templates *templates
+	// inFlight counts the messages being handled
+	inFlight atomic.Int64
+}
+
+func NewJudger(ms *mq.Service, registry *heartbeat.Registry) *Judger {
+	name := os.Getenv("JUDGER_NAME")
+	if name == "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			logger.Logger.Fatal("JUDGER_NAME is not set and failed to get hostname", zap.Error(err))
+		}
+		name = hostname
+	}
+
+	workers := 1
+	if envWorkers := os.Getenv("JUDGER_WORKERS"); envWorkers != "" {
+		var err error
+		workers, err = strconv.Atoi(envWorkers)
+		if err != nil || workers < 1 {
+			logger.Logger.Fatal("invalid JUDGER_WORKERS", zap.String("JUDGER_WORKERS", envWorkers), zap.Error(err))
+		}
+	}
+
+	engines := make(map[string]engine.Engine)
+	for _, dbName := range engine.Names() {
+		e, err := engine.Get(dbName)
+		if err != nil {
+			logger.Logger.Fatal("failed to get engine", zap.String("dbName", dbName), zap.Error(err))
+		}
+
+		env := dsnEnv(dbName)
+		dsn := os.Getenv(env)
+		if dsn == "" && !engine.IsEmbedded(e) {
+			logger.Logger.Warn("dsn is not set, skip engine", zap.String("dbName", dbName), zap.String("env", env))
+			continue
+		}
+
+		err = e.Connect(dsn)
+		if err != nil {
+			logger.Logger.Fatal("failed to connect engine", zap.String("dbName", dbName), zap.Error(err))
+		}
+
+		engines[dbName] = e
+		logger.Logger.Info("successfully connected engine", zap.String("dbName", dbName))
+	}
+
+	return &Judger{
+		name:      name,
+		workers:   workers,
+		engines:   engines,
+		ms:        ms,
+		registry:  registry,
+		templates: newTemplates(),
+	}
+}
+
+// dsnEnv returns the environment variable holding the dsn of an engine,
+// e.g. MYSQL_DSN for mysql.
+func dsnEnv(dbName string) string {
+	return strings.ToUpper(dbName) + "_DSN"
+}
+
+func (j *Judger) Close() {
+	err := j.registry.Unregister(j.name)
+	if err != nil {
+		logger.Logger.Error("failed to unregister judger", zap.String("name", j.name), zap.Error(err))
+	}
+
+	j.templates.Close()
+
+	for dbName, e := range j.engines {
+		err := e.Close()
+		if err != nil {
+			logger.Logger.Error("failed to close engine", zap.String("dbName", dbName), zap.Error(err))
+		}
+	}
+}
+
+// newSandbox returns a fresh sandbox of dbName with the PrepareSQL of the
+// answer and then of the dataset already run. When the answer is identified,
+// the sandbox is copied from a template of the dataset if possible.
+func (j *Judger) newSandbox(ctx context.Context, answer *model.JudgeAnswer, dataset *model.Dataset) (engine.Sandbox, error) {
+	e, ok := j.engines[answer.DBName]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrEngineNotConnected, answer.DBName)
+	}
+
+	if sb := j.templates.newSandbox(ctx, e, answer, dataset); sb != nil {
+		return sb, nil
+	}
+
+	sb, err := e.NewSandbox(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create sandbox: %w", err)
+	}
+
+	err = sb.Exec(ctx, answer.PrepareSQL)
+	if err != nil {
+		closeSandbox(sb)
+		return nil, fmt.Errorf("failed to run prepare sql: %w", err)
+	}
+
+	if dataset.PrepareSQL != "" {
+		err = sb.Exec(ctx, dataset.PrepareSQL)
+		if err != nil {
+			closeSandbox(sb)
+			return nil, fmt.Errorf("failed to run dataset prepare sql: %w", err)
+		}
+	}
+
+	return sb, nil
+}
+
+// restrict returns sb as a throwaway user that may only run the statements
+// allowed by the policy of problem, under limits. The submission and the
+// JudgeSQL inspecting it run there, while the PrepareSQL keeps running with
+// every privilege.
+func restrict(ctx context.Context, sb engine.Sandbox, problem *model.JudgeProblem, limits *engine.Limits) (engine.Sandbox, error) {
+	usb, err := sb.Restrict(ctx, problem.SQLPolicy.GetAllowedStatements())
+	if err != nil {
+		return nil, err
+	}
+
+	err = usb.SetLimits(ctx, limits)
+	if err != nil {
+		closeSandbox(usb)
+		return nil, err
+	}
+
+	return usb, nil
+}
+
+func closeSandbox(sb engine.Sandbox) {
+	err := sb.Close()
+	if err != nil {
+		logger.Logger.Error("failed to close sandbox", zap.Error(err))
+	}
+}
+
+func (j *Judger) Serve() {
+	logger.Logger.Info("judger is serving", zap.String("name", j.name), zap.Int("workers", j.workers))
+
+	go j.beat()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		j.work(mq.QueueAnswerGenerate, j.name, j.handleAnswerGenerate, j.giveUpAnswerGenerate)
+	}()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		j.work(mq.QueueRun, j.name, j.handleRun, j.giveUpRun)
+	}()
+	for i := 0; i < j.workers; i++ {
+		// each worker is a consumer of its own, so the messages pending for
+		// one of them are told apart from those of the others
+		consumerName := fmt.Sprintf("%s-%d", j.name, i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			j.work(mq.QueueSubmission, consumerName, j.handleSubmission, j.giveUpSubmission)
+		}()
+	}
+	wg.Wait()
+}
+
+// work handles the messages of queueName forever as consumerName. Messages
+// left pending by a crashed judger are reclaimed before new ones are read. A
+// message that has been delivered too many times is passed to giveUp
+// instead of handle, and dead-lettered once giveUp succeeds.
+func (j *Judger) work(queueName, consumerName string, handle func(msg *mq.Msg), giveUp func(msg *mq.Msg) error) {
+	reclaimArgs := map[string]interface{}{
+		"consumerName": consumerName,
+		"minIdle":      reclaimIdle,
+	}
+	dequeueArgs := map[string]interface{}{
+		"consumerName": consumerName,
+		"block":        dequeueBlock,
+	}
+
+	for {
+		msg, err := j.ms.Reclaim(queueName, reclaimArgs)
+		if errors.Is(err, mq.ErrNoMessageToDequeue) {
+			msg, err = j.ms.Dequeue(queueName, dequeueArgs)
+		}
+		if errors.Is(err, mq.ErrNoMessageToDequeue) {
+			continue
+		}
+		if err != nil {
+			time.Sleep(dequeueBlock)
+			continue
+		}
+
+		j.inFlight.Add(1)
+		stopTouching := touch(msg)
+		if msg.Deliveries <= maxDeliveries {
+			handle(msg)
+			stopTouching()
+			j.inFlight.Add(-1)
+			continue
+		}
+
+		err = giveUp(msg)
+		stopTouching()
+		j.inFlight.Add(-1)
+		if err != nil {
+			logger.Logger.Error("failed to give up message", zap.String("queue", queueName), zap.String("msgID", msg.ID), zap.Error(err))
+			continue
+		}
+
+		// the message is left pending when it cannot be dead-lettered, and
+		// given up again once reclaimed
+		err = j.ms.DeadLetter(queueName, msg)
+		if err != nil {
+			logger.Logger.Error("failed to dead-letter message", zap.String("queue", queueName), zap.String("msgID", msg.ID), zap.Error(err))
+		}
+	}
+}
+
+// touch touches msg every touchInterval until the returned function is
+// called, so that it is not reclaimed while it is handled.
+func touch(msg *mq.Msg) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(touchInterval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				err := msg.Touch()
+				if err != nil {
+					logger.Logger.Warn("failed to touch message", zap.String("msgID", msg.ID), zap.Error(err))
+				}
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+	}
+}
+
+// beat registers the judger in the heartbeat registry forever.
+func (j *Judger) beat() {
+	dbNames := make([]string, 0, len(j.engines))
+	for dbName := range j.engines {
+		dbNames = append(dbNames, dbName)
+	}
+	sort.Strings(dbNames)
+
+	for {
+		err := j.registry.Beat(&heartbeat.Judger{
+			Name:     j.name,
+			DBNames:  dbNames,
+			Version:  Version,
+			InFlight: j.inFlight.Load(),
+			LastSeen: time.Now(),
+		}, heartbeatTTL)
+		if err != nil {
+			logger.Logger.Error("failed to send heartbeat", zap.Error(err))
+		}
+
+		time.Sleep(heartbeatInterval)
+	}
+}
+
+func ack(msg *mq.Msg) {
+	err := msg.Ack()
+	if err != nil {
+		logger.Logger.Error("failed to ack message", zap.String("msgID", msg.ID), zap.Error(err))
+	}
+}
+
+func (j *Judger) handleSubmission(msg *mq.Msg) {
+	var req model.JudgeRequest
+	err := req.FromJSON(msg.Data)
+	if err != nil || req.Submission == nil || req.Problem == nil || req.Answer == nil {
+		logger.Logger.Error("drop invalid judge request", zap.String("msgID", msg.ID), zap.Error(err))
+		ack(msg)
+		return
+	}
+
+	submissionID := req.Submission.SubmissionID
+	judging := &model.JudgeResult{JudgeStatus: model.JudgeStatusJudging}
+	if err := j.publishResult(req.Submission, judging); err != nil {
+		logger.Logger.Warn("failed to publish judging status", zap.String("submissionID", submissionID), zap.Error(err))
+	}
+
+	err = j.publish(&model.JudgeResponse{
+		SubmissionID: submissionID,
+		RejudgeID:    req.Submission.RejudgeID,
+		AnswerID:     req.Answer.AnswerID,
+		Revision:     req.Answer.Revision,
+		RequestTime:  req.RequestTime,
+		Result:       j.judge(&req),
+	})
+	if err != nil {
+		logger.Logger.Error("failed to publish judge result", zap.String("submissionID", submissionID), zap.Error(err))
+		return
+	}
+
+	ack(msg)
+}
+
+// giveUpSubmission finishes a submission whose judge request keeps failing
+// with System Error.
+func (j *Judger) giveUpSubmission(msg *mq.Msg) error {
+	var req model.JudgeRequest
+	err := req.FromJSON(msg.Data)
+	if err != nil || req.Submission == nil {
+		return nil
+	}
+
+	return j.publishResult(req.Submission, systemError("judge request failed too many times"))
+}
+
+func (j *Judger) publishResult(submission *model.JudgeSubmission, result *model.JudgeResult) error {
+	return j.publish(&model.JudgeResponse{
+		SubmissionID: submission.SubmissionID,
+		RejudgeID:    submission.RejudgeID,
+		Result:       result,
+	})
+}
+
+func (j *Judger) publish(resp *model.JudgeResponse) error {
+	respJSON, err := resp.ToJSON()
+	if err != nil {
+		return fmt.Errorf("failed to marshal judge response: %w", err)
+	}
+
+	err = j.ms.Enqueue(mq.QueueJudgeResult, respJSON)
+	if err != nil {
+		return fmt.Errorf("failed to enqueue judge response: %w", err)
+	}
+
+	return nil
+}
+
+// judge runs the submission on every dataset of the answer. The verdict and
+// the output are Accepted when every dataset is, otherwise those of the
+// first dataset that is not; the score is the weighted share of the accepted
+// datasets. A submission violating the SQL policy or the construct rules of
+// the problem is not run, and scores nothing.
+func (j *Judger) judge(req *model.JudgeRequest) *model.JudgeResult {
+	// core checks the policy on submission already, this covers submissions
+	// made before the policy changed
+	err := req.Problem.SQLPolicy.Check(req.Submission.SubmittedSQL)
+	if err != nil {
+		return &model.JudgeResult{
+			JudgeStatus:  model.JudgeStatusPolicyViolation,
+			JudgerOutput: err.Error(),
+		}
+	}
+
+	// the constructs are known from the SQL alone, so a violation costs no
+	// dataset run
+	err = model.CheckConstructs(req.Submission.SubmittedSQL, req.Problem.RequiredConstructs, req.Problem.ForbiddenConstructs)
+	if err != nil {
+		return &model.JudgeResult{
+			JudgeStatus:  model.JudgeStatusConstructViolation,
+			JudgerOutput: err.Error(),
+		}
+	}
+
+	result := &model.JudgeResult{JudgeStatus: model.JudgeStatusAccepted}
+
+	var accepted, total float64
+	for _, dataset := range req.Answer.GetDatasets() {
+		dr := j.judgeDataset(req, dataset)
+		dr.IsSample = dataset.IsSample
+		result.Datasets = append(result.Datasets, dr)
+
+		if dr.TimeCost > result.TimeCost {
+			result.TimeCost = dr.TimeCost
+		}
+
+		weight := dataset.GetWeight()
+		total += weight
+		if dr.JudgeStatus == model.JudgeStatusAccepted {
+			accepted += weight
+			continue
+		}
+
+		if result.JudgeStatus == model.JudgeStatusAccepted {
+			result.JudgeStatus = dr.JudgeStatus
+			result.JudgerOutput = dr.JudgerOutput
+		}
+	}
+
+	if total > 0 {
+		result.Score = accepted / total
+	}
+
+	return result
+}
+
+func (j *Judger) judgeDataset(req *model.JudgeRequest, dataset *model.Dataset) *model.DatasetResult {
+	submissionID := req.Submission.SubmissionID
+	ctx := context.Background()
+
+	sb, err := j.newSandbox(ctx, req.Answer, dataset)
+	if err != nil {
+		logger.Logger.Error("failed to prepare sandbox", zap.String("submissionID", submissionID), zap.Error(err))
+		return datasetSystemError("failed to prepare sandbox")
+	}
+	defer closeSandbox(sb)
+
+	var expected engine.ResultSet
+	err = expected.FromJSON(dataset.AnswerOutput)
+	if err != nil {
+		logger.Logger.Error("failed to decode answer output", zap.String("submissionID", submissionID), zap.Error(err))
+		return datasetSystemError("invalid answer output")
+	}
+
+	limits := limitsOf(req.Problem)
+	err = sb.SetLimits(ctx, limits)
+	if err != nil {
+		logger.Logger.Error("failed to set limits", zap.String("submissionID", submissionID), zap.Error(err))
+		return datasetSystemError("failed to set limits")
+	}
+
+	usb, err := restrict(ctx, sb, req.Problem, limits)
+	if err != nil {
+		logger.Logger.Error("failed to restrict sandbox", zap.String("submissionID", submissionID), zap.Error(err))
+		return datasetSystemError("failed to restrict sandbox")
+	}
+	defer closeSandbox(usb)
+
+	// the grace period lets the database report its own timeout first
+	runCtx, cancel := context.WithTimeout(ctx, limits.Time+timeLimitGrace)
+	defer cancel()
+
+	start := time.Now()
+	actual, err := runSubmission(runCtx, usb, req.Submission.SubmittedSQL, req.Answer.JudgeSQL)
+	elapsed := time.Since(start)
+	timeCost := int32(elapsed.Milliseconds())
+	if status, output := classifyRunError(runCtx, err, elapsed, limits); status != "" {
+		return &model.DatasetResult{
+			JudgeStatus:  status,
+			TimeCost:     timeCost,
+			JudgerOutput: output,
+		}
+	}
+
+	if req.Answer.JudgeSQL != "" {
+		actual, err = inspect(ctx, usb, req.Answer.JudgeSQL)
+		if errors.Is(err, engine.ErrOutputLimitExceeded) {
+			return &model.DatasetResult{
+				JudgeStatus: model.JudgeStatusOutputLimitExceeded,
+				TimeCost:    timeCost,
+			}
+		}
+		if err != nil {
+			// the submission left the database in a state JudgeSQL cannot read,
+			// e.g. it dropped a table the answer keeps
+			logger.Logger.Info("failed to run judge sql", zap.String("submissionID", submissionID), zap.Error(err))
+			return &model.DatasetResult{
+				JudgeStatus:  model.JudgeStatusWrongAnswer,
+				TimeCost:     timeCost,
+				JudgerOutput: "failed to inspect the database state left by the submission",
+			}
+		}
+	}
+
+	diff := compare(&expected, actual, req.Answer.CompareOptions)
+	if diff == nil {
+		return &model.DatasetResult{
+			JudgeStatus: model.JudgeStatusAccepted,
+			TimeCost:    timeCost,
+		}
+	}
+
+	output, err := diff.ToJSON()
+	if err != nil {
+		logger.Logger.Error("failed to marshal diff", zap.String("submissionID", submissionID), zap.Error(err))
+	}
+	return &model.DatasetResult{
+		JudgeStatus:  model.JudgeStatusWrongAnswer,
+		TimeCost:     timeCost,
+		JudgerOutput: output,
+		Diff:         diff,
+	}
+}
+
+func limitsOf(problem *model.JudgeProblem) *engine.Limits {
+	return &engine.Limits{
+		Time:        time.Duration(problem.TimeLimit) * time.Millisecond,
+		Memory:      int64(problem.MemoryLimit) << 20,
+		OutputRows:  problem.GetOutputRowLimit(),
+		OutputBytes: problem.GetOutputSizeLimit(),
+	}
+}
+
+// classifyRunError returns the verdict and output of a submission that
+// failed with err after running for elapsed under limits, or an empty
+// verdict if it did not fail. Only the message of a compile error is kept:
+// it cannot tell anything about the data.
+func classifyRunError(runCtx context.Context, err error, elapsed time.Duration, limits *engine.Limits) (string, string) {
+	switch {
+	case errors.Is(err, engine.ErrCompileError):
+		return model.JudgeStatusCompileError, parserMessage(err)
+	case errors.Is(err, engine.ErrOutputLimitExceeded):
+		return model.JudgeStatusOutputLimitExceeded, ""
+	case errors.Is(err, engine.ErrMemoryLimitExceeded):
+		return model.JudgeStatusMemoryLimitExceeded, ""
+	case errors.Is(err, engine.ErrTimeLimitExceeded), errors.Is(runCtx.Err(), context.DeadlineExceeded), elapsed > limits.Time:
+		return model.JudgeStatusTimeLimitExceeded, ""
+	case err != nil:
+		return model.JudgeStatusRuntimeError, err.Error()
+	default:
+		return "", ""
+	}
+}
+
+// parserMessage returns the message of the database in a compile error,
+// without the context added on the way up.
+func parserMessage(err error) string {
+	msg := err.Error()
+	prefix := engine.ErrCompileError.Error() + ": "
+	if i := strings.Index(msg, prefix); i >= 0 {
+		return msg[i+len(prefix):]
+	}
+	return msg
+}
+
+// runSubmission runs the submitted SQL. Without judgeSQL the submission is a
+// query and its result set is returned; with judgeSQL it is a DML/DDL
+// statement whose effect is inspected afterwards, so nothing is returned.
+func runSubmission(ctx context.Context, sb engine.Sandbox, submittedSQL, judgeSQL string) (*engine.ResultSet, error) {
+	if judgeSQL == "" {
+		return sb.Query(ctx, submittedSQL)
+	}
+	return nil, sb.Exec(ctx, submittedSQL)
+}
+
+// inspect runs judgeSQL after a submission. It runs as the user of the
+// submission, on the restricted sandbox usb: the submission may have left
+// views or functions behind, which must not run with every privilege.
+func inspect(ctx context.Context, usb engine.Sandbox, judgeSQL string) (*engine.ResultSet, error) {
+	return usb.Query(ctx, judgeSQL)
+}
+
+func datasetSystemError(output string) *model.DatasetResult {
+	return &model.DatasetResult{
+		JudgeStatus:  model.JudgeStatusSystemError,
+		JudgerOutput: output,
+	}
+}
+
+func systemError(output string) *model.JudgeResult {
+	return &model.JudgeResult{
+		JudgeStatus:  model.JudgeStatusSystemError,
+		JudgerOutput: output,
+	}
+}
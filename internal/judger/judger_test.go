@@ -0,0 +1,204 @@
+package judger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/SQL-Online-Judge/backend/internal/judger/engine"
+	_ "github.com/SQL-Online-Judge/backend/internal/judger/engine/sqlite"
+	"github.com/SQL-Online-Judge/backend/internal/model"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/dialect"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/sqlparse"
+)
+
+// newSQLiteJudger returns a judger with only the embedded SQLite engine,
+// which needs no database server.
+func newSQLiteJudger(t *testing.T) *Judger {
+	t.Helper()
+
+	e, err := engine.Get(dialect.SQLite)
+	if err != nil {
+		t.Fatalf("failed to get sqlite engine: %v", err)
+	}
+	j := &Judger{
+		name:      "test",
+		engines:   map[string]engine.Engine{dialect.SQLite: e},
+		templates: newTemplates(),
+	}
+	t.Cleanup(j.templates.Close)
+	return j
+}
+
+// newSQLiteRequest returns a judge request against an answer listing the
+// rows of a table, with its output generated by j. A templated answer is
+// copied from a template of its database.
+func newSQLiteRequest(t *testing.T, j *Judger, templated bool, submittedSQL string) *model.JudgeRequest {
+	t.Helper()
+
+	answer := &model.JudgeAnswer{
+		DBName:     dialect.SQLite,
+		PrepareSQL: "CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT); INSERT INTO t VALUES (1, 'a'), (2, 'b');",
+		AnswerSQL:  "SELECT id, name FROM t ORDER BY id",
+		Datasets:   []*model.Dataset{{}},
+	}
+	if templated {
+		answer.AnswerID = 1
+		answer.Revision = 1
+	}
+
+	outputs, err := j.generate(answer)
+	if err != nil {
+		t.Fatalf("failed to generate answer output: %v", err)
+	}
+	answer.Datasets[0].AnswerOutput = outputs[0]
+
+	return &model.JudgeRequest{
+		Submission: &model.JudgeSubmission{SubmissionID: "1", SubmittedSQL: submittedSQL},
+		Problem:    &model.JudgeProblem{TimeLimit: 1000, MemoryLimit: 64},
+		Answer:     answer,
+	}
+}
+
+func TestJudgeSQLite(t *testing.T) {
+	j := newSQLiteJudger(t)
+	dir := t.TempDir()
+
+	tests := []struct {
+		name         string
+		templated    bool
+		submittedSQL string
+		want         string
+		// file must not exist after the submission ran
+		file string
+	}{
+		{name: "accepted", submittedSQL: "SELECT id, name FROM t ORDER BY id", want: model.JudgeStatusAccepted},
+		{name: "accepted from template", templated: true, submittedSQL: "SELECT * FROM t", want: model.JudgeStatusAccepted},
+		{name: "wrong answer", submittedSQL: "SELECT id, name FROM t WHERE id = 1", want: model.JudgeStatusWrongAnswer},
+		{
+			name:         "attach file",
+			submittedSQL: "ATTACH DATABASE '" + filepath.Join(dir, "attach.db") + "' AS x; SELECT id, name FROM t",
+			want:         model.JudgeStatusRuntimeError,
+			file:         filepath.Join(dir, "attach.db"),
+		},
+		{
+			name:         "attach shared memory",
+			templated:    true,
+			submittedSQL: "ATTACH DATABASE 'file:sqloj_1?mode=memory&cache=shared' AS x; SELECT id, name FROM t",
+			want:         model.JudgeStatusRuntimeError,
+		},
+		{name: "detach", submittedSQL: "DETACH DATABASE main", want: model.JudgeStatusRuntimeError},
+		{name: "pragma max page count", submittedSQL: "PRAGMA max_page_count = 1000000; SELECT id, name FROM t", want: model.JudgeStatusRuntimeError},
+		{name: "pragma in a comment", submittedSQL: "--'\nPRAGMA max_page_count = 1000000; SELECT id, name FROM t", want: model.JudgeStatusRuntimeError},
+		{
+			name:         "vacuum into",
+			submittedSQL: "VACUUM INTO '" + filepath.Join(dir, "vacuum.db") + "'",
+			want:         model.JudgeStatusRuntimeError,
+			file:         filepath.Join(dir, "vacuum.db"),
+		},
+		{
+			name:         "memory limit",
+			submittedSQL: "CREATE TABLE big AS WITH RECURSIVE r(n) AS (SELECT 1 UNION ALL SELECT n + 1 FROM r LIMIT 100000) SELECT zeroblob(4096) AS b FROM r; SELECT id, name FROM t",
+			want:         model.JudgeStatusMemoryLimitExceeded,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := j.judge(newSQLiteRequest(t, j, tt.templated, tt.submittedSQL))
+			if result.JudgeStatus != tt.want {
+				t.Errorf("judge status = %q (%s), want %q", result.JudgeStatus, result.JudgerOutput, tt.want)
+			}
+			if tt.want == model.JudgeStatusRuntimeError && !isDenied(result.JudgerOutput) {
+				t.Errorf("judger output = %q, want the statement to be denied", result.JudgerOutput)
+			}
+			if tt.file != "" {
+				if _, err := os.Stat(tt.file); !os.IsNotExist(err) {
+					t.Errorf("%s was created", tt.file)
+				}
+			}
+		})
+	}
+}
+
+func isDenied(output string) bool {
+	for _, msg := range []string{"not authorized", "authorization denied", "too many attached"} {
+		if strings.Contains(output, msg) {
+			return true
+		}
+	}
+	return false
+}
+
+// TestJudgeSQLiteJudgeSQL judges statements changing the database by the
+// state JudgeSQL reads from the restricted sandbox afterwards.
+func TestJudgeSQLiteJudgeSQL(t *testing.T) {
+	j := newSQLiteJudger(t)
+
+	answer := &model.JudgeAnswer{
+		DBName:     dialect.SQLite,
+		PrepareSQL: "CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT); INSERT INTO t VALUES (1, 'a'), (2, 'b');",
+		AnswerSQL:  "DELETE FROM t WHERE id = 2",
+		JudgeSQL:   "SELECT id, name FROM t ORDER BY id",
+		Datasets:   []*model.Dataset{{}},
+	}
+	outputs, err := j.generate(answer)
+	if err != nil {
+		t.Fatalf("failed to generate answer output: %v", err)
+	}
+	answer.Datasets[0].AnswerOutput = outputs[0]
+
+	tests := []struct {
+		name         string
+		submittedSQL string
+		want         string
+	}{
+		{name: "accepted", submittedSQL: "DELETE FROM t WHERE name = 'b'", want: model.JudgeStatusAccepted},
+		{name: "wrong answer", submittedSQL: "DELETE FROM t WHERE id = 1", want: model.JudgeStatusWrongAnswer},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := j.judge(&model.JudgeRequest{
+				Submission: &model.JudgeSubmission{SubmissionID: "1", SubmittedSQL: tt.submittedSQL},
+				Problem:    &model.JudgeProblem{TimeLimit: 1000, MemoryLimit: 64},
+				Answer:     answer,
+			})
+			if result.JudgeStatus != tt.want {
+				t.Errorf("judge status = %q (%s), want %q", result.JudgeStatus, result.JudgerOutput, tt.want)
+			}
+		})
+	}
+}
+
+// TestJudgeSQLiteConstructViolation checks that a construct violation is
+// found before any dataset is run.
+func TestJudgeSQLiteConstructViolation(t *testing.T) {
+	j := newSQLiteJudger(t)
+
+	tests := []struct {
+		name         string
+		submittedSQL string
+		want         string
+	}{
+		{name: "accepted", submittedSQL: "SELECT id, name FROM t ORDER BY id", want: model.JudgeStatusAccepted},
+		{name: "forbidden", submittedSQL: "SELECT id, name FROM t ORDER BY id LIMIT 2", want: model.JudgeStatusConstructViolation},
+		{name: "forbidden and failing", submittedSQL: "SELECT id, name FROM missing LIMIT 2", want: model.JudgeStatusConstructViolation},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := newSQLiteRequest(t, j, false, tt.submittedSQL)
+			req.Problem.ForbiddenConstructs = []string{sqlparse.ConstructLimit}
+
+			result := j.judge(req)
+			if result.JudgeStatus != tt.want {
+				t.Errorf("judge status = %q (%s), want %q", result.JudgeStatus, result.JudgerOutput, tt.want)
+			}
+			if tt.want == model.JudgeStatusConstructViolation && (len(result.Datasets) > 0 || result.Score != 0) {
+				t.Errorf("judged %d datasets scoring %v, want none run", len(result.Datasets), result.Score)
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package judger
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/SQL-Online-Judge/backend/internal/model"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/dialect"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/mq"
+)
+
+// memoryMQ keeps the queues in memory for the judger to work on, without
+// Redis. Only the methods the judger uses for its queues do anything.
+type memoryMQ struct {
+	lanes  map[string][]*mq.Msg
+	nextID int
+	acked  []string
+}
+
+func newMemoryMQ() *memoryMQ {
+	return &memoryMQ{lanes: make(map[string][]*mq.Msg)}
+}
+
+func (m *memoryMQ) IsQueueExists(queueName string) (bool, error) {
+	return true, nil
+}
+
+func (m *memoryMQ) CreateQueue(queueName string) error {
+	return nil
+}
+
+func (m *memoryMQ) Enqueue(queueName, msg string) error {
+	return m.EnqueueWithPriority(queueName, msg, mq.PriorityNormal)
+}
+
+func (m *memoryMQ) EnqueueWithPriority(queueName, msg string, priority mq.Priority) error {
+	m.nextID++
+	id := fmt.Sprintf("%d-0", m.nextID)
+	lane := mq.Lane(queueName, priority)
+	m.lanes[lane] = append(m.lanes[lane], &mq.Msg{
+		ID:         id,
+		Data:       msg,
+		Deliveries: 1,
+		Ack: func() error {
+			m.acked = append(m.acked, id)
+			return nil
+		},
+		Touch: func() error { return nil },
+	})
+	return nil
+}
+
+func (m *memoryMQ) Dequeue(queueName string, args map[string]interface{}) (*mq.Msg, error) {
+	for _, lane := range mq.Lanes(queueName) {
+		if len(m.lanes[lane]) > 0 {
+			msg := m.lanes[lane][0]
+			m.lanes[lane] = m.lanes[lane][1:]
+			return msg, nil
+		}
+	}
+	return nil, fmt.Errorf("%w", mq.ErrNoMessageToDequeue)
+}
+
+func (m *memoryMQ) Reclaim(queueName string, args map[string]interface{}) (*mq.Msg, error) {
+	return nil, fmt.Errorf("%w", mq.ErrNoMessageToDequeue)
+}
+
+func (m *memoryMQ) DeadLetter(queueName string, msg *mq.Msg) error {
+	return nil
+}
+
+func (m *memoryMQ) Reply(replyTo, msg string, ttl time.Duration) error {
+	return nil
+}
+
+func (m *memoryMQ) AwaitReply(replyTo string, timeout time.Duration) (string, error) {
+	return "", nil
+}
+
+func (m *memoryMQ) Publish(channel, msg string) error {
+	return nil
+}
+
+func (m *memoryMQ) Subscribe(ctx context.Context, channel string) (<-chan string, error) {
+	return nil, nil
+}
+
+func (m *memoryMQ) Stats(queueName string) ([]*mq.QueueStats, error) {
+	return nil, nil
+}
+
+func (m *memoryMQ) DeleteConsumer(lane, consumerName string) error {
+	return nil
+}
+
+// serveOne handles the next message of queueName as a worker would and
+// checks that it was acked.
+func serveOne(t *testing.T, m *memoryMQ, queueName string, handle func(msg *mq.Msg)) {
+	t.Helper()
+
+	msg, err := m.Dequeue(queueName, nil)
+	if err != nil {
+		t.Fatalf("failed to dequeue %s: %v", queueName, err)
+	}
+	handle(msg)
+
+	if len(m.acked) == 0 || m.acked[len(m.acked)-1] != msg.ID {
+		t.Errorf("message %s of %s was not acked", msg.ID, queueName)
+	}
+}
+
+// TestSubmissionLoop runs an answer through answer_generate and submissions
+// through submission to judge_result on SQLite, as the core and a judger do
+// over Redis.
+func TestSubmissionLoop(t *testing.T) {
+	m := newMemoryMQ()
+	j := newSQLiteJudger(t)
+	j.ms = mq.NewService(m)
+
+	answer := &model.JudgeAnswer{
+		AnswerID:   1,
+		Revision:   2,
+		DBName:     dialect.SQLite,
+		PrepareSQL: "CREATE TABLE t (id INTEGER PRIMARY KEY, name TEXT); INSERT INTO t VALUES (1, 'a'), (2, 'b');",
+		AnswerSQL:  "SELECT id, name FROM t ORDER BY id",
+		Datasets: []*model.Dataset{
+			{Weight: 1, IsSample: true},
+			{Weight: 1, PrepareSQL: "INSERT INTO t VALUES (3, 'c');"},
+		},
+	}
+
+	generateRequest := &model.AnswerGenerateRequest{AnswerID: "1", Revision: 2, Answer: answer}
+	generateJSON, err := generateRequest.ToJSON()
+	if err != nil {
+		t.Fatalf("failed to marshal answer generate request: %v", err)
+	}
+	m.Enqueue(mq.QueueAnswerGenerate, generateJSON)
+	serveOne(t, m, mq.QueueAnswerGenerate, j.handleAnswerGenerate)
+
+	outputMsg, err := m.Dequeue(mq.QueueAnswerOutput, nil)
+	if err != nil {
+		t.Fatalf("no answer output: %v", err)
+	}
+	var generateResponse model.AnswerGenerateResponse
+	err = generateResponse.FromJSON(outputMsg.Data)
+	if err != nil || generateResponse.Error != "" || len(generateResponse.AnswerOutputs) != 2 {
+		t.Fatalf("answer output = %+v, %v, want an output for both datasets", generateResponse, err)
+	}
+	for i, output := range generateResponse.AnswerOutputs {
+		answer.Datasets[i].AnswerOutput = output
+	}
+	answer.IsReady = true
+
+	tests := []struct {
+		name         string
+		submittedSQL string
+		policy       *model.SQLPolicy
+		want         string
+		wantScore    float64
+	}{
+		{name: "accepted", submittedSQL: "SELECT * FROM t", want: model.JudgeStatusAccepted, wantScore: 1},
+		{name: "hidden dataset failed", submittedSQL: "SELECT id, name FROM t WHERE id < 3", want: model.JudgeStatusWrongAnswer, wantScore: 0.5},
+		{name: "compile error", submittedSQL: "SELEC id FROM t", want: model.JudgeStatusCompileError},
+		{
+			name:         "policy violation",
+			submittedSQL: "DELETE FROM t WHERE id = 3; SELECT id, name FROM t",
+			policy:       &model.SQLPolicy{AllowedStatements: []string{"SELECT"}},
+			want:         model.JudgeStatusPolicyViolation,
+		},
+	}
+
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			submissionID := fmt.Sprintf("%d", i+1)
+			req := &model.JudgeRequest{
+				Submission:  &model.JudgeSubmission{SubmissionID: submissionID, SubmittedSQL: tt.submittedSQL, RejudgeID: 7},
+				Problem:     &model.JudgeProblem{TimeLimit: 1000, MemoryLimit: 64, SQLPolicy: tt.policy},
+				Answer:      answer,
+				RequestTime: time.UnixMilli(1700000000000),
+			}
+			reqJSON, err := req.ToJSON()
+			if err != nil {
+				t.Fatalf("failed to marshal judge request: %v", err)
+			}
+			m.EnqueueWithPriority(mq.QueueSubmission, reqJSON, mq.PriorityHigh)
+			serveOne(t, m, mq.QueueSubmission, j.handleSubmission)
+
+			var responses []*model.JudgeResponse
+			for {
+				msg, err := m.Dequeue(mq.QueueJudgeResult, nil)
+				if err != nil {
+					break
+				}
+				var resp model.JudgeResponse
+				err = resp.FromJSON(msg.Data)
+				if err != nil {
+					t.Fatalf("invalid judge response: %v", err)
+				}
+				responses = append(responses, &resp)
+			}
+			if len(responses) != 2 {
+				t.Fatalf("got %d judge responses, want Judging and the result", len(responses))
+			}
+
+			judging, final := responses[0], responses[1]
+			if judging.Result.JudgeStatus != model.JudgeStatusJudging {
+				t.Errorf("first status = %q, want %q", judging.Result.JudgeStatus, model.JudgeStatusJudging)
+			}
+			for _, resp := range responses {
+				if resp.SubmissionID != submissionID || resp.RejudgeID != 7 {
+					t.Errorf("response for submission %q rejudge %d, want %q rejudge 7", resp.SubmissionID, resp.RejudgeID, submissionID)
+				}
+			}
+			if final.AnswerID != 1 || final.Revision != 2 || !final.RequestTime.Equal(req.RequestTime) {
+				t.Errorf("result against answer %d revision %d as of %v, want answer 1 revision 2 as of %v", final.AnswerID, final.Revision, final.RequestTime, req.RequestTime)
+			}
+			if final.Result.JudgeStatus != tt.want || final.Result.Score != tt.wantScore {
+				t.Errorf("result = %q scoring %v (%s), want %q scoring %v", final.Result.JudgeStatus, final.Result.Score, final.Result.JudgerOutput, tt.want, tt.wantScore)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package judger
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/SQL-Online-Judge/backend/internal/judger/engine"
+	"github.com/SQL-Online-Judge/backend/internal/model"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/logger"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/mq"
+	"go.uber.org/zap"
+)
+
+// minReplyTTL keeps a reply around for a core that is slightly late to read
+// it.
+const minReplyTTL = 10 * time.Second
+
+func (j *Judger) handleRun(msg *mq.Msg) {
+	var req model.RunRequest
+	err := req.FromJSON(msg.Data)
+	if err != nil || req.ReplyTo == "" || req.Problem == nil || req.Answer == nil || req.Dataset == nil {
+		logger.Logger.Error("drop invalid run request", zap.String("msgID", msg.ID), zap.Error(err))
+		ack(msg)
+		return
+	}
+
+	if time.Now().After(req.Deadline) {
+		logger.Logger.Info("drop expired run request", zap.String("runID", req.RunID))
+		ack(msg)
+		return
+	}
+
+	err = j.reply(&req, j.run(&req))
+	if err != nil {
+		logger.Logger.Error("failed to reply run result", zap.String("runID", req.RunID), zap.Error(err))
+		return
+	}
+
+	ack(msg)
+}
+
+// giveUpRun answers a run request that keeps failing with System Error.
+func (j *Judger) giveUpRun(msg *mq.Msg) error {
+	var req model.RunRequest
+	err := req.FromJSON(msg.Data)
+	if err != nil || req.ReplyTo == "" {
+		return nil
+	}
+
+	return j.reply(&req, &model.RunResult{
+		JudgeStatus: model.JudgeStatusSystemError,
+		Output:      "run request failed too many times",
+	})
+}
+
+func (j *Judger) reply(req *model.RunRequest, result *model.RunResult) error {
+	resultJSON, err := result.ToJSON()
+	if err != nil {
+		return fmt.Errorf("failed to marshal run result: %w", err)
+	}
+
+	ttl := time.Until(req.Deadline)
+	if ttl < minReplyTTL {
+		ttl = minReplyTTL
+	}
+
+	return j.ms.Reply(req.ReplyTo, resultJSON, ttl)
+}
+
+// run runs the SQL on the sample dataset under the limits of the problem
+// and returns at most MaxRows rows of its output.
+func (j *Judger) run(req *model.RunRequest) *model.RunResult {
+	err := req.Problem.SQLPolicy.Check(req.SubmittedSQL)
+	if err != nil {
+		return &model.RunResult{
+			JudgeStatus: model.JudgeStatusPolicyViolation,
+			Output:      err.Error(),
+		}
+	}
+
+	ctx := context.Background()
+
+	sb, err := j.newSandbox(ctx, req.Answer, req.Dataset)
+	if err != nil {
+		logger.Logger.Error("failed to prepare sandbox", zap.String("runID", req.RunID), zap.Error(err))
+		return &model.RunResult{
+			JudgeStatus: model.JudgeStatusSystemError,
+			Output:      "failed to prepare sandbox",
+		}
+	}
+	defer closeSandbox(sb)
+
+	limits := limitsOf(req.Problem)
+	err = sb.SetLimits(ctx, limits)
+	if err != nil {
+		logger.Logger.Error("failed to set limits", zap.String("runID", req.RunID), zap.Error(err))
+		return &model.RunResult{
+			JudgeStatus: model.JudgeStatusSystemError,
+			Output:      "failed to set limits",
+		}
+	}
+
+	usb, err := restrict(ctx, sb, req.Problem, limits)
+	if err != nil {
+		logger.Logger.Error("failed to restrict sandbox", zap.String("runID", req.RunID), zap.Error(err))
+		return &model.RunResult{
+			JudgeStatus: model.JudgeStatusSystemError,
+			Output:      "failed to restrict sandbox",
+		}
+	}
+	defer closeSandbox(usb)
+
+	runCtx, cancel := context.WithTimeout(ctx, limits.Time+timeLimitGrace)
+	defer cancel()
+
+	start := time.Now()
+	rs, err := runSubmission(runCtx, usb, req.SubmittedSQL, req.Answer.JudgeSQL)
+	elapsed := time.Since(start)
+	result := &model.RunResult{TimeCost: int32(elapsed.Milliseconds())}
+	if status, output := classifyRunError(runCtx, err, elapsed, limits); status != "" {
+		result.JudgeStatus = status
+		result.Output = output
+		return result
+	}
+
+	// a statement changing the database returns nothing, the state it left
+	// is shown as JudgeSQL sees it: the sample output shows as much
+	if req.Answer.JudgeSQL != "" {
+		rs, err = inspect(ctx, usb, req.Answer.JudgeSQL)
+		if errors.Is(err, engine.ErrOutputLimitExceeded) {
+			result.JudgeStatus = model.JudgeStatusOutputLimitExceeded
+			return result
+		}
+		if err != nil {
+			logger.Logger.Info("failed to run judge sql", zap.String("runID", req.RunID), zap.Error(err))
+			result.JudgeStatus = model.JudgeStatusRuntimeError
+			result.Output = "failed to inspect the database state left by the statement"
+			return result
+		}
+	}
+
+	result.JudgeStatus = model.JudgeStatusAccepted
+
+	result.Columns = rs.Columns
+	result.Rows = rs.Rows
+	if req.MaxRows > 0 && len(result.Rows) > req.MaxRows {
+		result.Rows = result.Rows[:req.MaxRows]
+		result.Truncated = true
+	}
+
+	return result
+}
@@ -0,0 +1,246 @@
+package judger
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/SQL-Online-Judge/backend/internal/judger/engine"
+	"github.com/SQL-Online-Judge/backend/internal/model"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/logger"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/sqlparse"
+	"go.uber.org/zap"
+)
+
+// maxTemplates bounds the templates kept, the least recently used one is
+// dropped first.
+const maxTemplates = 128
+
+// templateKey identifies the PrepareSQL of a dataset. The datasets of an
+// answer have no id of their own, so they are told apart by their
+// PrepareSQL.
+type templateKey struct {
+	dbName   string
+	answerID int64
+	revision int64
+	dataset  [sha256.Size]byte
+}
+
+type template struct {
+	// ready is closed once the template is built; template is nil if that
+	// failed
+	ready    chan struct{}
+	template engine.Template
+	// refs counts the users of the template, it is closed once it is
+	// dropped and unused
+	refs     int
+	dropped  bool
+	lastUsed time.Time
+}
+
+// templates keeps a template of each dataset judged recently, so that a
+// sandbox is a copy of it instead of running the PrepareSQL again. The
+// templates of an answer are dropped once a newer revision of it shows up.
+type templates struct {
+	mu      sync.Mutex
+	entries map[templateKey]*template
+	// revisions holds the latest revision seen of each answer
+	revisions map[int64]int64
+}
+
+func newTemplates() *templates {
+	return &templates{
+		entries:   make(map[templateKey]*template),
+		revisions: make(map[int64]int64),
+	}
+}
+
+// newSandbox returns a copy of the template of the dataset, building the
+// template first if needed. It returns nil when there is no template to
+// copy, and the caller runs the PrepareSQL itself.
+func (ts *templates) newSandbox(ctx context.Context, e engine.Engine, answer *model.JudgeAnswer, dataset *model.Dataset) engine.Sandbox {
+	templater, ok := e.(engine.Templater)
+	if !ok || answer.AnswerID == 0 {
+		return nil
+	}
+
+	prepare := []string{answer.PrepareSQL}
+	if dataset.PrepareSQL != "" {
+		prepare = append(prepare, dataset.PrepareSQL)
+	}
+	if !isTemplatable(prepare) {
+		return nil
+	}
+
+	key := templateKey{
+		dbName:   answer.DBName,
+		answerID: answer.AnswerID,
+		revision: answer.Revision,
+		dataset:  sha256.Sum256([]byte(dataset.PrepareSQL)),
+	}
+	t := ts.acquire(ctx, templater, key, prepare)
+	if t == nil {
+		return nil
+	}
+	defer ts.release(t)
+
+	sb, err := t.template.NewSandbox(ctx)
+	if err != nil {
+		logger.Logger.Error("failed to copy template", zap.Int64("answerID", key.answerID), zap.Int64("revision", key.revision), zap.Error(err))
+		return nil
+	}
+	return sb
+}
+
+// acquire returns the template of key, building it if needed, or nil if
+// there is none. The template must be released after use.
+func (ts *templates) acquire(ctx context.Context, templater engine.Templater, key templateKey, prepare []string) *template {
+	ts.mu.Lock()
+
+	latest, ok := ts.revisions[key.answerID]
+	if ok && key.revision < latest {
+		// a request queued before the answer changed
+		ts.mu.Unlock()
+		return nil
+	}
+	if !ok || key.revision > latest {
+		ts.revisions[key.answerID] = key.revision
+		for k, t := range ts.entries {
+			if k.answerID == key.answerID {
+				ts.drop(k, t)
+			}
+		}
+	}
+
+	t, ok := ts.entries[key]
+	if ok {
+		t.refs++
+		t.lastUsed = time.Now()
+		ts.mu.Unlock()
+
+		<-t.ready
+		if t.template == nil {
+			ts.release(t)
+			return nil
+		}
+		return t
+	}
+
+	t = &template{ready: make(chan struct{}), refs: 1, lastUsed: time.Now()}
+	ts.entries[key] = t
+	if len(ts.entries) > maxTemplates {
+		ts.dropLeastRecentlyUsed()
+	}
+	ts.mu.Unlock()
+
+	built, err := templater.NewTemplate(ctx, prepare)
+	if err != nil {
+		logger.Logger.Info("failed to build template", zap.Int64("answerID", key.answerID), zap.Int64("revision", key.revision), zap.Error(err))
+		if !errors.Is(err, engine.ErrCannotTemplate) {
+			// the failure may be temporary, the next request tries again
+			ts.mu.Lock()
+			if ts.entries[key] == t {
+				delete(ts.entries, key)
+			}
+			ts.mu.Unlock()
+		}
+	}
+	t.template = built
+	close(t.ready)
+
+	if t.template == nil {
+		ts.release(t)
+		return nil
+	}
+	return t
+}
+
+func (ts *templates) release(t *template) {
+	ts.mu.Lock()
+	t.refs--
+	unused := t.dropped && t.refs == 0
+	ts.mu.Unlock()
+
+	if unused {
+		closeTemplate(t)
+	}
+}
+
+// drop forgets the template of key, which is closed once unused. The
+// caller holds ts.mu.
+func (ts *templates) drop(key templateKey, t *template) {
+	delete(ts.entries, key)
+	t.dropped = true
+	if t.refs == 0 {
+		go closeTemplate(t)
+	}
+}
+
+func (ts *templates) dropLeastRecentlyUsed() {
+	var lruKey templateKey
+	var lru *template
+	for k, t := range ts.entries {
+		if lru == nil || t.lastUsed.Before(lru.lastUsed) {
+			lruKey, lru = k, t
+		}
+	}
+	if lru != nil {
+		ts.drop(lruKey, lru)
+	}
+}
+
+// Close drops every template.
+func (ts *templates) Close() {
+	ts.mu.Lock()
+	var unused []*template
+	for k, t := range ts.entries {
+		delete(ts.entries, k)
+		t.dropped = true
+		if t.refs == 0 {
+			unused = append(unused, t)
+		}
+	}
+	ts.mu.Unlock()
+
+	for _, t := range unused {
+		closeTemplate(t)
+	}
+}
+
+func closeTemplate(t *template) {
+	if t.template == nil {
+		return
+	}
+	err := t.template.Close()
+	if err != nil {
+		logger.Logger.Error("failed to close template", zap.Error(err))
+	}
+}
+
+// isTemplatable reports whether the prepare statements leave nothing but
+// the database behind: a template loses the session state, e.g. variables
+// and temporary tables.
+func isTemplatable(prepare []string) bool {
+	for _, sql := range prepare {
+		for _, opts := range sqlparse.AllOptions {
+			for _, statement := range sqlparse.Split(sql, opts) {
+				switch statement.Kind() {
+				case "SET", "USE", "PRAGMA", "PREPARE", "DECLARE":
+					return false
+				case "CREATE":
+					for _, t := range statement.Tokens[1:] {
+						if t.IsWord("TABLE") {
+							break
+						}
+						if t.IsWord("TEMPORARY") || t.IsWord("TEMP") {
+							return false
+						}
+					}
+				}
+			}
+		}
+	}
+	return true
+}
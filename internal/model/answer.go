@@ -1,33 +1,113 @@
 package model
 
 import (
+	"strconv"
 	"unicode/utf8"
 
+	"github.com/SQL-Online-Judge/backend/internal/pkg/dialect"
 	"github.com/SQL-Online-Judge/backend/internal/pkg/id"
 )
 
-type Answer struct {
-	AnswerID     int64  `bson:"answerID"`
-	ProblemID    int64  `bson:"problemID"`
-	DBName       string `bson:"dbName"`
-	PrepareSQL   string `bson:"prepareSQL"`
-	AnswerSQL    string `bson:"answerSQL"`
-	JudgeSQL     string `bson:"judgeSQL"`
-	AnswerOutput string `bson:"answerOutput"`
-	IsReady      bool   `bson:"isReady"`
-	ImageName    string `bson:"imageName"`
-	Deleted      bool   `bson:"deleted"`
+const (
+	ColumnMatchName           = "name"
+	ColumnMatchNameIgnoreCase = "name_ignore_case"
+	ColumnMatchPosition       = "position"
+)
+
+// CompareOptions controls how the output of a submission is compared with
+// the answer output. The zero value compares exactly: rows in order, columns
+// by case-sensitive name, no normalization.
+type CompareOptions struct {
+	IgnoreOrder    bool    `bson:"ignoreOrder" json:"ignoreOrder"`
+	ColumnMatch    string  `bson:"columnMatch" json:"columnMatch"`
+	FloatTolerance float64 `bson:"floatTolerance" json:"floatTolerance"`
+	NormalizeSpace bool    `bson:"normalizeSpace" json:"normalizeSpace"`
+	NullAsEmpty    bool    `bson:"nullAsEmpty" json:"nullAsEmpty"`
 }
 
-func (a *Answer) IsValidDBName() bool {
-	switch a.DBName {
-	case "mysql", "opengauss":
+func (co *CompareOptions) IsValidColumnMatch() bool {
+	switch co.ColumnMatch {
+	case "", ColumnMatchName, ColumnMatchNameIgnoreCase, ColumnMatchPosition:
 		return true
 	default:
 		return false
 	}
 }
 
+func (co *CompareOptions) IsValidFloatTolerance() bool {
+	return co.FloatTolerance >= 0 && co.FloatTolerance <= 1
+}
+
+func (co *CompareOptions) IsValidCompareOptions() bool {
+	return co.IsValidColumnMatch() && co.IsValidFloatTolerance()
+}
+
+const MaxDatasets = 16
+
+// Dataset is one set of test data of an answer. Its PrepareSQL runs after
+// the PrepareSQL of the answer, so the answer can create the schema and the
+// datasets fill it.
+type Dataset struct {
+	PrepareSQL string `bson:"prepareSQL" json:"prepareSQL"`
+	// Weight is the share of the dataset in the score, 0 counts as 1.
+	Weight float64 `bson:"weight" json:"weight"`
+	// IsSample makes the dataset and its output visible to students; the
+	// other datasets are hidden and students only learn if they passed.
+	IsSample     bool   `bson:"isSample" json:"isSample"`
+	AnswerOutput string `bson:"answerOutput" json:"answerOutput"`
+}
+
+func (d *Dataset) IsValidPrepareSQL() bool {
+	sqlLen := utf8.RuneCountInString(d.PrepareSQL)
+	return sqlLen <= 65536
+}
+
+func (d *Dataset) IsValidWeight() bool {
+	return d.Weight >= 0 && d.Weight <= 100
+}
+
+func (d *Dataset) IsValidDataset() bool {
+	return d.IsValidPrepareSQL() && d.IsValidWeight()
+}
+
+func (d *Dataset) GetWeight() float64 {
+	if d.Weight == 0 {
+		return 1
+	}
+	return d.Weight
+}
+
+// Sample is a sample dataset as shown to students: the SQL creating its
+// tables and the expected output.
+type Sample struct {
+	DBName       string
+	PrepareSQL   string
+	AnswerOutput string
+}
+
+type Answer struct {
+	AnswerID       int64           `bson:"answerID"`
+	ProblemID      int64           `bson:"problemID"`
+	DBName         string          `bson:"dbName"`
+	PrepareSQL     string          `bson:"prepareSQL"`
+	AnswerSQL      string          `bson:"answerSQL"`
+	JudgeSQL       string          `bson:"judgeSQL"`
+	CompareOptions *CompareOptions `bson:"compareOptions"`
+	// Datasets is empty for an answer with a single dataset, which is its
+	// PrepareSQL alone.
+	Datasets      []*Dataset `bson:"datasets"`
+	AnswerOutput  string     `bson:"answerOutput"`
+	IsReady       bool       `bson:"isReady"`
+	Revision      int64      `bson:"revision"`
+	GenerateError string     `bson:"generateError"`
+	ImageName     string     `bson:"imageName"`
+	Deleted       bool       `bson:"deleted"`
+}
+
+func (a *Answer) IsValidDBName() bool {
+	return dialect.IsSupported(a.DBName)
+}
+
 func (a *Answer) IsValidPrepareSQL() bool {
 	sqlLen := utf8.RuneCountInString(a.PrepareSQL)
 	return sqlLen >= 2 && sqlLen <= 65536
@@ -43,21 +123,80 @@ func (a *Answer) IsValidJudgeSQL() bool {
 	return sqlLen <= 65536
 }
 
+func (a *Answer) IsValidCompareOptions() bool {
+	return a.CompareOptions == nil || a.CompareOptions.IsValidCompareOptions()
+}
+
+func (a *Answer) IsValidDatasets() bool {
+	if len(a.Datasets) > MaxDatasets {
+		return false
+	}
+	for _, d := range a.Datasets {
+		if d == nil || !d.IsValidDataset() {
+			return false
+		}
+	}
+	return true
+}
+
 func (a *Answer) IsValidAnswer() bool {
-	return a.IsValidDBName() && a.IsValidPrepareSQL() && a.IsValidAnswerSQL() && a.IsValidJudgeSQL()
+	return a.IsValidDBName() && a.IsValidPrepareSQL() && a.IsValidAnswerSQL() && a.IsValidJudgeSQL() && a.IsValidCompareOptions() && a.IsValidDatasets()
+}
+
+// GetSamples returns the sample datasets of the answer. They are available
+// only once the answer output is generated.
+func (a *Answer) GetSamples() []*Sample {
+	if !a.IsReady {
+		return nil
+	}
+
+	var samples []*Sample
+	for _, d := range a.Datasets {
+		if !d.IsSample {
+			continue
+		}
+		prepareSQL := a.PrepareSQL
+		if d.PrepareSQL != "" {
+			prepareSQL += "\n" + d.PrepareSQL
+		}
+		samples = append(samples, &Sample{
+			DBName:       a.DBName,
+			PrepareSQL:   prepareSQL,
+			AnswerOutput: d.AnswerOutput,
+		})
+	}
+	return samples
+}
+
+func (a *Answer) ToGenerateRequest() *AnswerGenerateRequest {
+	return &AnswerGenerateRequest{
+		AnswerID: strconv.FormatInt(a.AnswerID, 10),
+		Revision: a.Revision,
+		Answer: &JudgeAnswer{
+			DBName:     a.DBName,
+			PrepareSQL: a.PrepareSQL,
+			AnswerSQL:  a.AnswerSQL,
+			JudgeSQL:   a.JudgeSQL,
+			Datasets:   a.Datasets,
+		},
+	}
 }
 
 func NewAnswer(a *Answer) *Answer {
 	return &Answer{
-		AnswerID:     id.NewID(),
-		ProblemID:    a.ProblemID,
-		DBName:       a.DBName,
-		PrepareSQL:   a.PrepareSQL,
-		AnswerSQL:    a.AnswerSQL,
-		JudgeSQL:     a.JudgeSQL,
-		AnswerOutput: a.AnswerOutput,
-		IsReady:      a.IsReady,
-		ImageName:    a.ImageName,
-		Deleted:      a.Deleted,
+		AnswerID:       id.NewID(),
+		ProblemID:      a.ProblemID,
+		DBName:         a.DBName,
+		PrepareSQL:     a.PrepareSQL,
+		AnswerSQL:      a.AnswerSQL,
+		JudgeSQL:       a.JudgeSQL,
+		CompareOptions: a.CompareOptions,
+		Datasets:       a.Datasets,
+		AnswerOutput:   a.AnswerOutput,
+		IsReady:        a.IsReady,
+		Revision:       a.Revision,
+		GenerateError:  a.GenerateError,
+		ImageName:      a.ImageName,
+		Deleted:        a.Deleted,
 	}
 }
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/SQL-Online-Judge/backend/internal/pkg/sqlparse"
+)
+
+var ErrConstructViolation = fmt.Errorf("construct violation")
+
+func isValidConstructs(constructs []string) bool {
+	for _, construct := range constructs {
+		if !sqlparse.IsConstruct(construct) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckConstructs returns an error wrapping ErrConstructViolation that
+// explains the first construct sql fails to use among required, or uses among
+// forbidden. A construct counts as used if any dialect reads sql as using it.
+func CheckConstructs(sql string, required, forbidden []string) error {
+	if len(required) == 0 && len(forbidden) == 0 {
+		return nil
+	}
+
+	used := make(map[string]bool)
+	for _, opts := range sqlparse.AllOptions {
+		for construct := range sqlparse.Constructs(sqlparse.Split(sql, opts)) {
+			used[construct] = true
+		}
+	}
+
+	for _, construct := range required {
+		if !used[construct] {
+			return fmt.Errorf("%w: the solution must use %s", ErrConstructViolation, construct)
+		}
+	}
+	for _, construct := range forbidden {
+		if used[construct] {
+			return fmt.Errorf("%w: the solution must not use %s", ErrConstructViolation, construct)
+		}
+	}
+
+	return nil
+}
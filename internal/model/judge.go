@@ -3,53 +3,222 @@ package model
 import (
 	"encoding/json"
 	"fmt"
+	"time"
 )
 
 const (
-	JudgeStatusPending           = "Pending"
-	JudgeStatusQueued            = "Queued"
-	JudgeStatusJudging           = "Judging"
-	JudgeStatusAccepted          = "Accepted"
-	JudgeStatusWrongAnswer       = "Wrong Answer"
-	JudgeStatusTimeLimitExceeded = "Time Limit Exceeded"
-	JudgeStatusRuntimeError      = "Runtime Error"
-	JudgeStatusSystemError       = "System Error"
+	JudgeStatusPending             = "Pending"
+	JudgeStatusQueued              = "Queued"
+	JudgeStatusJudging             = "Judging"
+	JudgeStatusAccepted            = "Accepted"
+	JudgeStatusWrongAnswer         = "Wrong Answer"
+	JudgeStatusTimeLimitExceeded   = "Time Limit Exceeded"
+	JudgeStatusMemoryLimitExceeded = "Memory Limit Exceeded"
+	JudgeStatusRuntimeError        = "Runtime Error"
+	JudgeStatusCompileError        = "Compile Error"
+	JudgeStatusOutputLimitExceeded = "Output Limit Exceeded"
+	JudgeStatusSystemError         = "System Error"
+	JudgeStatusPolicyViolation     = "Policy Violation"
+	// JudgeStatusConstructViolation is SQL that does not use the constructs
+	// the problem requires, or uses forbidden ones. It is not run.
+	JudgeStatusConstructViolation = "Construct Violation"
 )
 
 type JudgeSubmission struct {
 	SubmissionID string `bson:"submissionID" json:"submissionID"`
 	SubmittedSQL string `bson:"submittedSQL" json:"submittedSQL"`
+	// RejudgeID is the rejudge the request was made for, 0 for the first
+	// judge. The results are sent back with it, so the results of an
+	// earlier request are told apart.
+	RejudgeID int64 `bson:"rejudgeID" json:"rejudgeID,string,omitempty"`
 }
 
 type JudgeProblem struct {
-	TimeLimit   int32 `bson:"timeLimit" json:"timeLimit"`
-	MemoryLimit int32 `bson:"memoryLimit" json:"memoryLimit"`
+	TimeLimit           int32      `bson:"timeLimit" json:"timeLimit"`
+	MemoryLimit         int32      `bson:"memoryLimit" json:"memoryLimit"`
+	SQLPolicy           *SQLPolicy `bson:"sqlPolicy" json:"sqlPolicy"`
+	RequiredConstructs  []string   `bson:"requiredConstructs" json:"requiredConstructs"`
+	ForbiddenConstructs []string   `bson:"forbiddenConstructs" json:"forbiddenConstructs"`
+	OutputRowLimit      int32      `bson:"outputRowLimit" json:"outputRowLimit"`
+	OutputSizeLimit     int32      `bson:"outputSizeLimit" json:"outputSizeLimit"`
+}
+
+// GetOutputRowLimit returns the most rows a submission may return.
+func (jp *JudgeProblem) GetOutputRowLimit() int64 {
+	if jp.OutputRowLimit == 0 {
+		return DefaultOutputRowLimit
+	}
+	return int64(jp.OutputRowLimit)
+}
+
+// GetOutputSizeLimit returns the most bytes a submission may return.
+func (jp *JudgeProblem) GetOutputSizeLimit() int64 {
+	if jp.OutputSizeLimit == 0 {
+		return DefaultOutputSizeLimit << 10
+	}
+	return int64(jp.OutputSizeLimit) << 10
 }
 
 type JudgeAnswer struct {
-	DBName       string `bson:"dbName" json:"dbName"`
-	PrepareSQL   string `bson:"prepareSQL" json:"prepareSQL"`
-	AnswerSQL    string `bson:"answerSQL" json:"answerSQL"`
-	JudgeSQL     string `bson:"judgeSQL" json:"judgeSQL"`
-	IsReady      bool   `bson:"isReady" json:"-"`
-	AnswerOutput string `bson:"answerOutput" json:"answerOutput"`
+	// AnswerID and Revision identify the PrepareSQL, they are not set in
+	// an AnswerGenerateRequest.
+	AnswerID       int64           `bson:"answerID" json:"answerID,string"`
+	Revision       int64           `bson:"revision" json:"revision"`
+	DBName         string          `bson:"dbName" json:"dbName"`
+	PrepareSQL     string          `bson:"prepareSQL" json:"prepareSQL"`
+	AnswerSQL      string          `bson:"answerSQL" json:"answerSQL"`
+	JudgeSQL       string          `bson:"judgeSQL" json:"judgeSQL"`
+	CompareOptions *CompareOptions `bson:"compareOptions" json:"compareOptions"`
+	Datasets       []*Dataset      `bson:"datasets" json:"datasets"`
+	IsReady        bool            `bson:"isReady" json:"-"`
+	GenerateError  string          `bson:"generateError" json:"-"`
+	AnswerOutput   string          `bson:"answerOutput" json:"answerOutput"`
+}
+
+// GetDatasets returns the datasets to judge on. An answer without datasets
+// has a single one: no extra PrepareSQL and the answer output. It is hidden,
+// its output was never published to students.
+func (ja *JudgeAnswer) GetDatasets() []*Dataset {
+	if len(ja.Datasets) == 0 {
+		return []*Dataset{{Weight: 1, AnswerOutput: ja.AnswerOutput}}
+	}
+	return ja.Datasets
+}
+
+const (
+	DiffReasonColumnCount = "column_count"
+	DiffReasonColumnName  = "column_name"
+	DiffReasonRowCount    = "row_count"
+	DiffReasonRow         = "row"
+)
+
+// Diff tells how the output of a submission differs from the answer output.
+// Row indexes count from 0 in the order the rows are compared, which is the
+// sorted order when the answer ignores the row order.
+type Diff struct {
+	Reason           string    `bson:"reason" json:"reason"`
+	ExpectedColumns  []string  `bson:"expectedColumns" json:"expectedColumns,omitempty"`
+	ActualColumns    []string  `bson:"actualColumns" json:"actualColumns,omitempty"`
+	ExpectedRowCount int       `bson:"expectedRowCount" json:"expectedRowCount"`
+	ActualRowCount   int       `bson:"actualRowCount" json:"actualRowCount"`
+	MissingRowCount  int       `bson:"missingRowCount" json:"missingRowCount"`
+	ExtraRowCount    int       `bson:"extraRowCount" json:"extraRowCount"`
+	FirstDiffRow     int       `bson:"firstDiffRow" json:"firstDiffRow"`
+	ExpectedRow      []*string `bson:"expectedRow" json:"expectedRow,omitempty"`
+	ActualRow        []*string `bson:"actualRow" json:"actualRow,omitempty"`
+}
+
+func (d *Diff) ToJSON() (string, error) {
+	j, err := json.Marshal(d)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal Diff: %w", err)
+	}
+	return string(j), nil
+}
+
+// Filter returns the part of the diff students may see at visibility: nil
+// for none, the reason and the counts for summary, everything for full.
+func (d *Diff) Filter(visibility string) *Diff {
+	if d == nil {
+		return nil
+	}
+
+	switch visibility {
+	case DiffVisibilityFull:
+		return d
+	case DiffVisibilityNone:
+		return nil
+	default:
+		return &Diff{
+			Reason:           d.Reason,
+			ExpectedRowCount: d.ExpectedRowCount,
+			ActualRowCount:   d.ActualRowCount,
+			MissingRowCount:  d.MissingRowCount,
+			ExtraRowCount:    d.ExtraRowCount,
+			FirstDiffRow:     d.FirstDiffRow,
+		}
+	}
+}
+
+type DatasetResult struct {
+	IsSample     bool   `bson:"isSample" json:"isSample"`
+	JudgeStatus  string `bson:"judgeStatus" json:"judgeStatus"`
+	TimeCost     int32  `bson:"timeCost" json:"timeCost"`
+	JudgerOutput string `bson:"judgerOutput" json:"judgerOutput"`
+	// Diff is set on Wrong Answer, JudgerOutput then holds it as JSON.
+	Diff *Diff `bson:"diff" json:"diff"`
 }
 
 type JudgeResult struct {
 	JudgeStatus  string `bson:"judgeStatus" json:"judgeStatus"`
 	TimeCost     int32  `bson:"timeCost" json:"timeCost"`
 	JudgerOutput string `bson:"judgerOutput" json:"judgerOutput"`
+	// Score is the weighted share of the datasets accepted, from 0 to 1.
+	Score    float64          `bson:"score" json:"score"`
+	Datasets []*DatasetResult `bson:"datasets" json:"datasets"`
+}
+
+func (jr *JudgeResult) IsFinal() bool {
+	switch jr.JudgeStatus {
+	case JudgeStatusPending, JudgeStatusQueued, JudgeStatusJudging:
+		return false
+	default:
+		return true
+	}
+}
+
+// IsReproducible reports whether judging the same SQL again gives the same
+// result. A System Error or a Time Limit Exceeded on any dataset may depend
+// on the load of the judger instead.
+func (jr *JudgeResult) IsReproducible() bool {
+	if !jr.IsFinal() || jr.JudgeStatus == JudgeStatusSystemError || jr.JudgeStatus == JudgeStatusTimeLimitExceeded {
+		return false
+	}
+	for _, dataset := range jr.Datasets {
+		if dataset.JudgeStatus == JudgeStatusSystemError || dataset.JudgeStatus == JudgeStatusTimeLimitExceeded {
+			return false
+		}
+	}
+	return true
 }
 
 type JudgeRequest struct {
 	Submission *JudgeSubmission `json:"submission"`
 	Problem    *JudgeProblem    `json:"problem"`
 	Answer     *JudgeAnswer     `json:"answer"`
+	// RequestTime is when the problem and the answer were read to build
+	// the request.
+	RequestTime time.Time `json:"requestTime"`
 }
 
 type JudgeResponse struct {
-	SubmissionID string       `json:"submissionID"`
-	Result       *JudgeResult `json:"result"`
+	SubmissionID string `json:"submissionID"`
+	// RejudgeID is that of the judge request.
+	RejudgeID int64 `json:"rejudgeID,string,omitempty"`
+	// AnswerID and Revision identify the answer the submission was judged
+	// against, they are set with the final result only.
+	AnswerID int64 `json:"answerID,string,omitempty"`
+	Revision int64 `json:"revision,omitempty"`
+	// RequestTime is that of the judge request, it is set with the final
+	// result only.
+	RequestTime time.Time    `json:"requestTime"`
+	Result      *JudgeResult `json:"result"`
+}
+
+type AnswerGenerateRequest struct {
+	AnswerID string       `json:"answerID"`
+	Revision int64        `json:"revision"`
+	Answer   *JudgeAnswer `json:"answer"`
+}
+
+type AnswerGenerateResponse struct {
+	AnswerID     string `json:"answerID"`
+	Revision     int64  `json:"revision"`
+	AnswerOutput string `json:"answerOutput"`
+	// AnswerOutputs holds the output of every dataset, AnswerOutput is the
+	// first of them.
+	AnswerOutputs []string `json:"answerOutputs"`
+	Error         string   `json:"error"`
 }
 
 func (jr *JudgeRequest) ToJSON() (string, error) {
@@ -83,3 +252,35 @@ func (jr *JudgeResponse) FromJSON(j string) error {
 	}
 	return nil
 }
+
+func (agr *AnswerGenerateRequest) ToJSON() (string, error) {
+	j, err := json.Marshal(agr)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal AnswerGenerateRequest: %w", err)
+	}
+	return string(j), nil
+}
+
+func (agr *AnswerGenerateRequest) FromJSON(j string) error {
+	err := json.Unmarshal([]byte(j), agr)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal AnswerGenerateRequest: %w", err)
+	}
+	return nil
+}
+
+func (agr *AnswerGenerateResponse) ToJSON() (string, error) {
+	j, err := json.Marshal(agr)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal AnswerGenerateResponse: %w", err)
+	}
+	return string(j), nil
+}
+
+func (agr *AnswerGenerateResponse) FromJSON(j string) error {
+	err := json.Unmarshal([]byte(j), agr)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal AnswerGenerateResponse: %w", err)
+	}
+	return nil
+}
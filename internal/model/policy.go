@@ -0,0 +1,160 @@
+package model
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/SQL-Online-Judge/backend/internal/pkg/sqlparse"
+)
+
+const maxPolicyEntries = 32
+
+var ErrPolicyViolation = fmt.Errorf("policy violation")
+
+// SQLPolicy restricts what the SQL submitted to a problem may contain. The
+// zero value allows everything but changing the settings of the session.
+type SQLPolicy struct {
+	// AllowedStatements are the statement kinds allowed, e.g. SELECT; empty
+	// allows every kind.
+	AllowedStatements []string `bson:"allowedStatements" json:"allowedStatements"`
+	// ForbiddenKeywords are forbidden words and names, e.g. a helper table.
+	ForbiddenKeywords  []string `bson:"forbiddenKeywords" json:"forbiddenKeywords"`
+	ForbiddenFunctions []string `bson:"forbiddenFunctions" json:"forbiddenFunctions"`
+	SingleStatement    bool     `bson:"singleStatement" json:"singleStatement"`
+}
+
+func isValidPolicyEntries(entries []string) bool {
+	if len(entries) > maxPolicyEntries {
+		return false
+	}
+	for _, entry := range entries {
+		entryLen := utf8.RuneCountInString(entry)
+		if entryLen < 1 || entryLen > 64 || strings.ContainsAny(entry, " \t\r\n") {
+			return false
+		}
+	}
+	return true
+}
+
+func (sp *SQLPolicy) IsValidSQLPolicy() bool {
+	return isValidPolicyEntries(sp.AllowedStatements) && isValidPolicyEntries(sp.ForbiddenKeywords) && isValidPolicyEntries(sp.ForbiddenFunctions)
+}
+
+// GetAllowedStatements returns the statement kinds allowed, empty when every
+// kind is. A nil policy allows everything.
+func (sp *SQLPolicy) GetAllowedStatements() []string {
+	if sp == nil {
+		return nil
+	}
+	return sp.AllowedStatements
+}
+
+// Check returns an error wrapping ErrPolicyViolation that explains the
+// first violation of the policy by sql. Dialects quote differently, so sql
+// is checked as read by each of them. Every policy, even nil, forbids
+// changing the settings of the session, which hold the limits of the
+// judger.
+func (sp *SQLPolicy) Check(sql string) error {
+	// MySQL reads SET_VAR in optimizer hints, which are comments to sqlparse
+	if strings.Contains(strings.ToUpper(sql), "SET_VAR") {
+		return fmt.Errorf("%w: SET_VAR is forbidden", ErrPolicyViolation)
+	}
+
+	for _, opts := range sqlparse.AllOptions {
+		statements := sqlparse.Split(sql, opts)
+		err := checkSession(statements)
+		if err != nil {
+			return err
+		}
+		if sp == nil {
+			continue
+		}
+		err = sp.check(statements)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkSession returns an error wrapping ErrPolicyViolation for the first
+// statement that may change a setting of the session. SET is allowed for
+// user variables only, e.g. SET @n = 0.
+func checkSession(statements []*sqlparse.Statement) error {
+	for _, statement := range statements {
+		switch kind := statement.Kind(); {
+		case kind == "SET" && !setsUserVariables(statement.Tokens[1:]):
+			return fmt.Errorf("%w: SET is only allowed for user variables", ErrPolicyViolation)
+		case kind == "RESET":
+			return fmt.Errorf("%w: RESET statements are not allowed", ErrPolicyViolation)
+		case kind == "ALTER" && len(statement.Tokens) > 1 && statement.Tokens[1].IsWord("SESSION"):
+			return fmt.Errorf("%w: ALTER SESSION is not allowed", ErrPolicyViolation)
+		}
+
+		for _, function := range statement.Functions() {
+			if function == "SET_CONFIG" {
+				return fmt.Errorf("%w: function %s is forbidden", ErrPolicyViolation, function)
+			}
+		}
+	}
+	return nil
+}
+
+// setsUserVariables reports whether every assignment of a SET statement,
+// given without the SET, is to a user variable: @ and a name, not @@.
+func setsUserVariables(tokens []sqlparse.Token) bool {
+	if len(tokens) == 0 {
+		return false
+	}
+
+	depth := 0
+	for i := range tokens {
+		isTarget := i == 0 || depth == 0 && tokens[i-1].IsSymbol(",")
+		if isTarget && (!tokens[i].IsSymbol("@") || i+1 == len(tokens) || !tokens[i+1].IsName()) {
+			return false
+		}
+		switch {
+		case tokens[i].IsSymbol("("):
+			depth++
+		case tokens[i].IsSymbol(")"):
+			depth--
+		}
+	}
+	return true
+}
+
+func (sp *SQLPolicy) check(statements []*sqlparse.Statement) error {
+	if sp.SingleStatement && len(statements) > 1 {
+		return fmt.Errorf("%w: only a single statement is allowed", ErrPolicyViolation)
+	}
+
+	for _, statement := range statements {
+		if kind := statement.Kind(); len(sp.AllowedStatements) > 0 && !containsFold(sp.AllowedStatements, kind) {
+			return fmt.Errorf("%w: %s statements are not allowed", ErrPolicyViolation, kind)
+		}
+
+		for _, name := range statement.Names() {
+			if containsFold(sp.ForbiddenKeywords, name) {
+				return fmt.Errorf("%w: %s is forbidden", ErrPolicyViolation, name)
+			}
+		}
+
+		for _, function := range statement.Functions() {
+			if containsFold(sp.ForbiddenFunctions, function) {
+				return fmt.Errorf("%w: function %s is forbidden", ErrPolicyViolation, function)
+			}
+		}
+	}
+
+	return nil
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
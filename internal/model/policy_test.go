@@ -0,0 +1,60 @@
+package model
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSQLPolicyCheck(t *testing.T) {
+	selectOnly := &SQLPolicy{AllowedStatements: []string{"SELECT"}}
+
+	tests := []struct {
+		name   string
+		policy *SQLPolicy
+		sql    string
+		ok     bool
+	}{
+		{name: "nil policy", policy: nil, sql: "DROP TABLE t", ok: true},
+		{name: "allowed", policy: selectOnly, sql: "select * from t; WITH a AS (SELECT 1) SELECT * FROM a", ok: true},
+		{name: "not allowed", policy: selectOnly, sql: "SELECT 1; DROP TABLE t", ok: false},
+		{name: "with delete", policy: selectOnly, sql: "WITH a AS (SELECT 1) DELETE FROM t", ok: false},
+		{name: "quoted semicolon", policy: selectOnly, sql: "SELECT ';DROP TABLE t'", ok: true},
+		{name: "mysql hash comment", policy: selectOnly, sql: "SELECT 1 # '\nFROM secret; DROP TABLE x; -- '", ok: false},
+		{name: "mysql executable comment", policy: selectOnly, sql: "SELECT 1 /*!; DROP TABLE x */", ok: false},
+		{name: "dash comment without space", policy: selectOnly, sql: "SELECT 1 --'\n; DROP TABLE x; --'", ok: false},
+		{name: "nested comment", policy: selectOnly, sql: "SELECT 1 /* /* */ ' */; DROP TABLE x; --'", ok: false},
+		{name: "escape string", policy: selectOnly, sql: "SELECT E'\\''; DROP TABLE x; --'", ok: false},
+		{name: "backslash escape", policy: selectOnly, sql: "SELECT '\\'; DROP TABLE x; -- '", ok: false},
+		{name: "bracket identifier", policy: selectOnly, sql: "SELECT 1 AS [']; DROP TABLE x; --[']", ok: false},
+		{name: "dollar quote", policy: selectOnly, sql: "SELECT $$'$$; DROP TABLE x; --'", ok: false},
+		{name: "single statement", policy: &SQLPolicy{SingleStatement: true}, sql: "SELECT 1; SELECT 2", ok: false},
+		{name: "single statement with semicolon", policy: &SQLPolicy{SingleStatement: true}, sql: "SELECT 1;", ok: true},
+		{name: "forbidden keyword", policy: &SQLPolicy{ForbiddenKeywords: []string{"secret"}}, sql: "SELECT * FROM `Secret`", ok: false},
+		{name: "forbidden keyword in comment", policy: &SQLPolicy{ForbiddenKeywords: []string{"secret"}}, sql: "SELECT 1 /* secret */", ok: true},
+		{name: "forbidden keyword after hash", policy: &SQLPolicy{ForbiddenKeywords: []string{"secret"}}, sql: "SELECT 1 #\nFROM secret", ok: false},
+		{name: "forbidden function", policy: &SQLPolicy{ForbiddenFunctions: []string{"sleep"}}, sql: "SELECT SLEEP(1)", ok: false},
+		{name: "forbidden function as name", policy: &SQLPolicy{ForbiddenFunctions: []string{"sleep"}}, sql: "SELECT sleep FROM t", ok: true},
+		{name: "user variables", policy: nil, sql: "SET @n := 0, @m = (SELECT MAX(a) FROM t); SELECT @n", ok: true},
+		{name: "session variable", policy: nil, sql: "SET SESSION max_heap_table_size = 1073741824", ok: false},
+		{name: "session variable after user variable", policy: nil, sql: "SET @n = 0, tmp_table_size = 1073741824", ok: false},
+		{name: "system variable", policy: nil, sql: "SET @@max_execution_time = 0", ok: false},
+		{name: "set local", policy: nil, sql: "SET LOCAL query_max_mem = '1GB'", ok: false},
+		{name: "reset", policy: nil, sql: "RESET statement_timeout", ok: false},
+		{name: "alter session", policy: nil, sql: "ALTER SESSION SET statement_timeout = 0", ok: false},
+		{name: "set_config", policy: nil, sql: "SELECT set_config('statement_timeout', '0', false)", ok: false},
+		{name: "set_var hint", policy: nil, sql: "SELECT /*+ SET_VAR(max_execution_time = 0) */ * FROM t", ok: false},
+		{name: "update set", policy: nil, sql: "UPDATE t SET a = 1", ok: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Check(tt.sql)
+			if tt.ok && err != nil {
+				t.Errorf("Check(%q) = %v, want nil", tt.sql, err)
+			}
+			if !tt.ok && !errors.Is(err, ErrPolicyViolation) {
+				t.Errorf("Check(%q) = %v, want a policy violation", tt.sql, err)
+			}
+		})
+	}
+}
@@ -6,6 +6,17 @@ import (
 	"github.com/SQL-Online-Judge/backend/internal/pkg/id"
 )
 
+const (
+	DefaultOutputRowLimit  = 10000
+	DefaultOutputSizeLimit = 1024 // in KiB
+)
+
+const (
+	DiffVisibilityNone    = "none"
+	DiffVisibilitySummary = "summary"
+	DiffVisibilityFull    = "full"
+)
+
 type Problem struct {
 	ProblemID   int64    `bson:"problemID"`
 	AuthorID    int64    `bson:"authorID"`
@@ -14,7 +25,19 @@ type Problem struct {
 	Content     string   `bson:"content"`
 	TimeLimit   int32    `bson:"timeLimit"`
 	MemoryLimit int32    `bson:"memoryLimit"`
-	Deleted     bool     `bson:"deleted"`
+	// OutputRowLimit and OutputSizeLimit, in KiB, bound the output of a
+	// submission, 0 means the default.
+	OutputRowLimit  int32 `bson:"outputRowLimit"`
+	OutputSizeLimit int32 `bson:"outputSizeLimit"`
+	// DiffVisibility is how much of the diff of a Wrong Answer students see,
+	// empty means summary.
+	DiffVisibility string     `bson:"diffVisibility"`
+	SQLPolicy      *SQLPolicy `bson:"sqlPolicy"`
+	// RequiredConstructs and ForbiddenConstructs are checked once the result
+	// of a submission is accepted, see sqlparse for the constructs.
+	RequiredConstructs  []string `bson:"requiredConstructs"`
+	ForbiddenConstructs []string `bson:"forbiddenConstructs"`
+	Deleted             bool     `bson:"deleted"`
 }
 
 func (p *Problem) IsValidTitle() bool {
@@ -48,19 +71,54 @@ func (p *Problem) IsValidMemoryLimit() bool {
 	return p.MemoryLimit >= 200 && p.MemoryLimit <= 4096
 }
 
+func (p *Problem) IsValidOutputLimits() bool {
+	return p.OutputRowLimit >= 0 && p.OutputRowLimit <= 1000000 && p.OutputSizeLimit >= 0 && p.OutputSizeLimit <= 65536
+}
+
+func (p *Problem) IsValidDiffVisibility() bool {
+	switch p.DiffVisibility {
+	case "", DiffVisibilityNone, DiffVisibilitySummary, DiffVisibilityFull:
+		return true
+	default:
+		return false
+	}
+}
+
+func (p *Problem) IsValidSQLPolicy() bool {
+	return p.SQLPolicy == nil || p.SQLPolicy.IsValidSQLPolicy()
+}
+
+func (p *Problem) IsValidConstructs() bool {
+	if !isValidConstructs(p.RequiredConstructs) || !isValidConstructs(p.ForbiddenConstructs) {
+		return false
+	}
+	for _, construct := range p.RequiredConstructs {
+		if containsFold(p.ForbiddenConstructs, construct) {
+			return false
+		}
+	}
+	return true
+}
+
 func (p *Problem) IsValidProblem() bool {
-	return p.IsValidTitle() && p.IsValidTags() && p.IsValidContent() && p.IsValidTimeLimit() && p.IsValidMemoryLimit()
+	return p.IsValidTitle() && p.IsValidTags() && p.IsValidContent() && p.IsValidTimeLimit() && p.IsValidMemoryLimit() && p.IsValidOutputLimits() && p.IsValidDiffVisibility() && p.IsValidSQLPolicy() && p.IsValidConstructs()
 }
 
 func NewProblem(p *Problem) *Problem {
 	return &Problem{
-		ProblemID:   id.NewID(),
-		AuthorID:    p.AuthorID,
-		Title:       p.Title,
-		Tags:        p.Tags,
-		Content:     p.Content,
-		TimeLimit:   p.TimeLimit,
-		MemoryLimit: p.MemoryLimit,
-		Deleted:     false,
+		ProblemID:           id.NewID(),
+		AuthorID:            p.AuthorID,
+		Title:               p.Title,
+		Tags:                p.Tags,
+		Content:             p.Content,
+		TimeLimit:           p.TimeLimit,
+		MemoryLimit:         p.MemoryLimit,
+		OutputRowLimit:      p.OutputRowLimit,
+		OutputSizeLimit:     p.OutputSizeLimit,
+		DiffVisibility:      p.DiffVisibility,
+		SQLPolicy:           p.SQLPolicy,
+		RequiredConstructs:  p.RequiredConstructs,
+		ForbiddenConstructs: p.ForbiddenConstructs,
+		Deleted:             false,
 	}
 }
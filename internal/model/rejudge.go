@@ -0,0 +1,39 @@
+package model
+
+import (
+	"time"
+
+	"github.com/SQL-Online-Judge/backend/internal/pkg/id"
+)
+
+const (
+	RejudgeScopeSubmission = "submission"
+	RejudgeScopeProblem    = "problem"
+	RejudgeScopeTask       = "task"
+)
+
+// Rejudge records a teacher re-running the submissions of a scope: a single
+// submission, a problem (optionally limited to one dialect) or a task.
+type Rejudge struct {
+	RejudgeID  int64     `bson:"rejudgeID"`
+	CreatorID  int64     `bson:"creatorID"`
+	CreateTime time.Time `bson:"createTime"`
+	Scope      string    `bson:"scope"`
+	TargetID   int64     `bson:"targetID"`
+	DBName     string    `bson:"dbName"`
+	Total      int64     `bson:"total"`
+	// Done is not stored, it is counted from the submissions.
+	Done int64 `bson:"-"`
+}
+
+func NewRejudge(r *Rejudge) *Rejudge {
+	return &Rejudge{
+		RejudgeID:  id.NewID(),
+		CreatorID:  r.CreatorID,
+		CreateTime: time.Now(),
+		Scope:      r.Scope,
+		TargetID:   r.TargetID,
+		DBName:     r.DBName,
+		Total:      0,
+	}
+}
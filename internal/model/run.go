@@ -0,0 +1,80 @@
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// MaxRunRows caps the rows returned by a run.
+const MaxRunRows = 100
+
+// RunRequest asks a judger to run SQL on a sample dataset and send the
+// output to ReplyTo. Nothing is recorded, and a request still queued after
+// Deadline is dropped since no one waits for it any longer.
+type RunRequest struct {
+	RunID        string        `json:"runID"`
+	ReplyTo      string        `json:"replyTo"`
+	Deadline     time.Time     `json:"deadline"`
+	MaxRows      int           `json:"maxRows"`
+	SubmittedSQL string        `json:"submittedSQL"`
+	Problem      *JudgeProblem `json:"problem"`
+	Answer       *JudgeAnswer  `json:"answer"`
+	Dataset      *Dataset      `json:"dataset"`
+}
+
+// RunResult is the output of a run. JudgeStatus is Accepted when the SQL
+// ran, whatever it returned.
+type RunResult struct {
+	JudgeStatus string      `json:"judgeStatus"`
+	TimeCost    int32       `json:"timeCost"`
+	Columns     []string    `json:"columns"`
+	Rows        [][]*string `json:"rows"`
+	// Truncated tells that there were more than MaxRows rows.
+	Truncated bool   `json:"truncated"`
+	Output    string `json:"output"`
+}
+
+func (rr *RunRequest) ToJSON() (string, error) {
+	j, err := json.Marshal(rr)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal RunRequest: %w", err)
+	}
+	return string(j), nil
+}
+
+func (rr *RunRequest) FromJSON(j string) error {
+	err := json.Unmarshal([]byte(j), rr)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal RunRequest: %w", err)
+	}
+	return nil
+}
+
+func (rr *RunResult) ToJSON() (string, error) {
+	j, err := json.Marshal(rr)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal RunResult: %w", err)
+	}
+	return string(j), nil
+}
+
+func (rr *RunResult) FromJSON(j string) error {
+	err := json.Unmarshal([]byte(j), rr)
+	if err != nil {
+		return fmt.Errorf("failed to unmarshal RunResult: %w", err)
+	}
+	return nil
+}
+
+// GetSampleDataset returns the first sample dataset of the answer, or nil
+// if there is none. The implicit dataset of an answer without datasets does
+// not count, its data is not shown to students.
+func (ja *JudgeAnswer) GetSampleDataset() *Dataset {
+	for _, dataset := range ja.Datasets {
+		if dataset.IsSample {
+			return dataset
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package model
+
+import (
+	"time"
+
+	"github.com/SQL-Online-Judge/backend/internal/pkg/id"
+)
+
+const (
+	SimilarityStatusRunning = "Running"
+	SimilarityStatusDone    = "Done"
+	SimilarityStatusFailed  = "Failed"
+)
+
+// SimilarityReport records a teacher looking for copied submissions in a
+// task: the pairs of students whose accepted SQL of a problem is alike.
+type SimilarityReport struct {
+	ReportID   int64     `bson:"reportID"`
+	TaskID     int64     `bson:"taskID"`
+	CreatorID  int64     `bson:"creatorID"`
+	CreateTime time.Time `bson:"createTime"`
+	FinishTime time.Time `bson:"finishTime"`
+	Status     string    `bson:"status"`
+	Error      string    `bson:"error"`
+	// Submissions counts the submissions compared.
+	Submissions int64          `bson:"submissions"`
+	Pairs       []*SimilarPair `bson:"pairs"`
+}
+
+// SimilarPair is two submissions of a problem, by different students, whose
+// SQL is alike. Similarity is from 0 to 1.
+type SimilarPair struct {
+	ProblemID   int64                `bson:"problemID"`
+	Similarity  float64              `bson:"similarity"`
+	Submissions []*SimilarSubmission `bson:"submissions"`
+}
+
+type SimilarSubmission struct {
+	SubmissionID int64     `bson:"submissionID"`
+	StudentID    int64     `bson:"studentID"`
+	Username     string    `bson:"username"`
+	ProblemID    int64     `bson:"problemID"`
+	DBName       string    `bson:"dbName"`
+	SubmitTime   time.Time `bson:"submitTime"`
+	SubmittedSQL string    `bson:"submittedSQL"`
+}
+
+func NewSimilarityReport(r *SimilarityReport) *SimilarityReport {
+	return &SimilarityReport{
+		ReportID:   id.NewID(),
+		TaskID:     r.TaskID,
+		CreatorID:  r.CreatorID,
+		CreateTime: time.Now(),
+		Status:     SimilarityStatusRunning,
+		Pairs:      []*SimilarPair{},
+	}
+}
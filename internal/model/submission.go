@@ -1,10 +1,17 @@
 package model
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
 	"time"
 	"unicode/utf8"
 
+	"github.com/SQL-Online-Judge/backend/internal/pkg/dialect"
 	"github.com/SQL-Online-Judge/backend/internal/pkg/id"
+	"github.com/SQL-Online-Judge/backend/internal/pkg/sqlparse"
 )
 
 type Submission struct {
@@ -15,18 +22,44 @@ type Submission struct {
 	ProblemID    int64     `bson:"problemID"`
 	DBName       string    `bson:"dbName"`
 	SubmittedSQL string    `bson:"submittedSQL"`
-	JudgeStatus  string    `bson:"judgeStatus"`
-	TimeCost     int32     `bson:"timeCost"`
-	JudgerOutput string    `bson:"judgerOutput"`
+	// Fingerprint is the same for SubmittedSQL differing only in layout.
+	Fingerprint  string `bson:"fingerprint"`
+	JudgeStatus  string `bson:"judgeStatus"`
+	TimeCost     int32  `bson:"timeCost"`
+	JudgerOutput string `bson:"judgerOutput"`
+	// Score is the weighted share of the datasets accepted, from 0 to 1.
+	Score          float64          `bson:"score"`
+	DatasetResults []*DatasetResult `bson:"datasetResults"`
+	// RejudgeID is the last rejudge that re-ran the submission.
+	RejudgeID int64 `bson:"rejudgeID,omitempty"`
+	// Outbox is set until the judge request is published to the queue.
+	Outbox *SubmissionOutbox `bson:"outbox,omitempty"`
 }
 
-func (s *Submission) IsValidDBName() bool {
-	switch s.DBName {
-	case "mysql", "opengauss":
-		return true
-	default:
-		return false
+// SubmissionOutbox is the outbox entry of a submission. It lives in the
+// submission document so both are written atomically. The judge request is
+// built from the submission when it is published.
+type SubmissionOutbox struct {
+	Attempts        int32     `bson:"attempts"`
+	NextAttemptTime time.Time `bson:"nextAttemptTime"`
+	LastError       string    `bson:"lastError"`
+	// Priority is the mq.Priority the judge request is published with.
+	Priority int `bson:"priority"`
+}
+
+// Fingerprint returns the fingerprint of sql, the hash of sql normalized as
+// read by every dialect.
+func Fingerprint(sql string) string {
+	h := sha256.New()
+	for _, opts := range sqlparse.AllOptions {
+		h.Write([]byte(sqlparse.Normalize(sql, opts)))
+		h.Write([]byte{0})
 	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (s *Submission) IsValidDBName() bool {
+	return dialect.IsSupported(s.DBName)
 }
 
 func (s *Submission) IsValidSubmittedSQL() bool {
@@ -36,13 +69,27 @@ func (s *Submission) IsValidSubmittedSQL() bool {
 
 func (s *Submission) IsValidJudgeStatus() bool {
 	switch s.JudgeStatus {
-	case "Pending", "Queued", "Judging", "Accepted", "Wrong Answer", "Time Limit Exceeded", "Runtime Error", "System Error":
+	case "Pending", "Queued", "Judging", "Accepted", "Wrong Answer", "Time Limit Exceeded", "Memory Limit Exceeded", "Runtime Error", "Compile Error", "Output Limit Exceeded", "System Error", "Policy Violation", "Construct Violation":
 		return true
 	default:
 		return false
 	}
 }
 
+// IsRejudgeable reports whether the submission may be rejudged: it is not
+// being judged already, and it was not rejected by the policy on
+// submission, which leaves a violation but no dataset result.
+func (s *Submission) IsRejudgeable() bool {
+	switch s.JudgeStatus {
+	case JudgeStatusPending, JudgeStatusQueued, JudgeStatusJudging:
+		return false
+	case JudgeStatusPolicyViolation:
+		return len(s.DatasetResults) > 0
+	default:
+		return true
+	}
+}
+
 func (s *Submission) IsValidSubmission() bool {
 	return s.IsValidDBName() && s.IsValidSubmittedSQL() && s.IsValidJudgeStatus()
 }
@@ -56,10 +103,40 @@ func NewSubmission(s *Submission) *Submission {
 		ProblemID:    s.ProblemID,
 		DBName:       s.DBName,
 		SubmittedSQL: s.SubmittedSQL,
+		Fingerprint:  Fingerprint(s.SubmittedSQL),
 		JudgeStatus:  "Pending",
 		TimeCost:     0,
 		JudgerOutput: "",
+		Score:        0,
+	}
+}
+
+// SubmissionStatus tells the submitter that the status of a submission
+// changed.
+type SubmissionStatus struct {
+	SubmissionID string `json:"submissionID"`
+	TaskID       string `json:"taskID"`
+	ProblemID    string `json:"problemID"`
+	JudgeStatus  string `json:"judgeStatus"`
+	TimeCost     int32  `json:"timeCost"`
+}
+
+func NewSubmissionStatus(s *Submission) *SubmissionStatus {
+	return &SubmissionStatus{
+		SubmissionID: strconv.FormatInt(s.SubmissionID, 10),
+		TaskID:       strconv.FormatInt(s.TaskID, 10),
+		ProblemID:    strconv.FormatInt(s.ProblemID, 10),
+		JudgeStatus:  s.JudgeStatus,
+		TimeCost:     s.TimeCost,
+	}
+}
+
+func (ss *SubmissionStatus) ToJSON() (string, error) {
+	j, err := json.Marshal(ss)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal SubmissionStatus: %w", err)
 	}
+	return string(j), nil
 }
 
 type SubmissionSummary struct {
@@ -72,9 +149,15 @@ type SubmissionSummary struct {
 	DBName       string    `bson:"dbName"`
 	JudgeStatus  string    `bson:"judgeStatus"`
 	TimeCost     int32     `bson:"timeCost"`
+	// Score is the part of the score of the task problem earned.
+	Score float64 `bson:"score"`
 }
 
 type SubmitedSQL struct {
-	SubmissionID int64  `bson:"submissionID"`
-	SubmittedSQL string `bson:"submittedSQL"`
+	SubmissionID   int64            `bson:"submissionID"`
+	ProblemID      int64            `bson:"problemID"`
+	SubmittedSQL   string           `bson:"submittedSQL"`
+	JudgeStatus    string           `bson:"judgeStatus"`
+	JudgerOutput   string           `bson:"judgerOutput"`
+	DatasetResults []*DatasetResult `bson:"datasetResults"`
 }
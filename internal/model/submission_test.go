@@ -0,0 +1,30 @@
+package model
+
+import "testing"
+
+func TestSubmissionIsRejudgeable(t *testing.T) {
+	judged := []*DatasetResult{{JudgeStatus: JudgeStatusAccepted}}
+
+	tests := []struct {
+		status   string
+		datasets []*DatasetResult
+		want     bool
+	}{
+		{status: JudgeStatusPending, want: false},
+		{status: JudgeStatusQueued, want: false},
+		{status: JudgeStatusJudging, want: false},
+		{status: JudgeStatusAccepted, datasets: judged, want: true},
+		{status: JudgeStatusWrongAnswer, datasets: judged, want: true},
+		{status: JudgeStatusSystemError, want: true},
+		{status: JudgeStatusCompileError, want: true},
+		{status: JudgeStatusPolicyViolation, want: false},
+		{status: JudgeStatusConstructViolation, want: true},
+	}
+
+	for _, tt := range tests {
+		s := &Submission{JudgeStatus: tt.status, DatasetResults: tt.datasets}
+		if got := s.IsRejudgeable(); got != tt.want {
+			t.Errorf("IsRejudgeable() of %s with %d dataset results = %v, want %v", tt.status, len(tt.datasets), got, tt.want)
+		}
+	}
+}
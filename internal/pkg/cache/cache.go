@@ -0,0 +1,113 @@
+// Package cache keeps values in Redis, so that every core process shares
+// them.
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+var ErrCacheMiss = fmt.Errorf("cache miss")
+
+// Cache keeps values for ttl in groups, so that every value of a group can
+// be invalidated at once.
+type Cache struct {
+	rdb    *redis.Client
+	prefix string
+	ttl    time.Duration
+}
+
+func NewCache(rdb *redis.Client, prefix string, ttl time.Duration) *Cache {
+	return &Cache{
+		rdb:    rdb,
+		prefix: prefix,
+		ttl:    ttl,
+	}
+}
+
+func (c *Cache) valueKey(group, key string) string {
+	return c.prefix + ":" + group + ":" + key
+}
+
+// keysKey is the set of the value keys of group.
+func (c *Cache) keysKey(group string) string {
+	return c.prefix + ":" + group
+}
+
+// invalidatedKey holds when group was last invalidated, in unix ms.
+func (c *Cache) invalidatedKey(group string) string {
+	return c.prefix + "-invalidated:" + group
+}
+
+// Get returns the value of key in group or ErrCacheMiss.
+func (c *Cache) Get(group, key string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	value, err := c.rdb.Get(ctx, c.valueKey(group, key)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", fmt.Errorf("%w", ErrCacheMiss)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get cached value: %w", err)
+	}
+
+	return value, nil
+}
+
+// setScript stores the value unless the group was invalidated after it was
+// computed.
+var setScript = redis.NewScript(`
+local invalidated = redis.call('GET', KEYS[1])
+if invalidated and tonumber(invalidated) > tonumber(ARGV[1]) then
+	return 0
+end
+redis.call('SET', KEYS[2], ARGV[2], 'PX', ARGV[3])
+redis.call('SADD', KEYS[3], KEYS[2])
+redis.call('PEXPIRE', KEYS[3], ARGV[3])
+return 1
+`)
+
+// Set stores value as key in group. asOf is when the value was computed
+// from the data it depends on: a value computed before the last
+// invalidation of the group is stale and is not stored.
+func (c *Cache) Set(group, key, value string, asOf time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	keys := []string{c.invalidatedKey(group), c.valueKey(group, key), c.keysKey(group)}
+	err := setScript.Run(ctx, c.rdb, keys, asOf.UnixMilli(), value, c.ttl.Milliseconds()).Err()
+	if err != nil {
+		return fmt.Errorf("failed to set cached value: %w", err)
+	}
+
+	return nil
+}
+
+var invalidateScript = redis.NewScript(`
+redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+local keys = redis.call('SMEMBERS', KEYS[2])
+for _, key in ipairs(keys) do
+	redis.call('DEL', key)
+end
+redis.call('DEL', KEYS[2])
+return #keys
+`)
+
+// Invalidate deletes every value of group.
+func (c *Cache) Invalidate(group string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	keys := []string{c.invalidatedKey(group), c.keysKey(group)}
+	err := invalidateScript.Run(ctx, c.rdb, keys, time.Now().UnixMilli(), c.ttl.Milliseconds()).Err()
+	if err != nil {
+		return fmt.Errorf("failed to invalidate cached values: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+// Package dialect lists the SQL dialects a submission may be written in,
+// with how each is tokenized. It links no database driver, so core can
+// check dialect names without the judger engines.
+package dialect
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/SQL-Online-Judge/backend/internal/pkg/sqlparse"
+)
+
+var (
+	mu      sync.RWMutex
+	options = make(map[string]sqlparse.Options)
+)
+
+// Register adds the dialect name, tokenized with opts. It is meant to be
+// called from the init function of the file that defines the dialect and
+// panics on duplicates.
+func Register(name string, opts sqlparse.Options) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, ok := options[name]; ok {
+		panic(fmt.Errorf("dialect %s is already registered", name))
+	}
+	options[name] = opts
+}
+
+// Names returns the sorted names of all dialects.
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(options))
+	for name := range options {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func IsSupported(name string) bool {
+	_, ok := Options(name)
+	return ok
+}
+
+// Options returns how the dialect name is tokenized.
+func Options(name string) (sqlparse.Options, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	opts, ok := options[name]
+	return opts, ok
+}
@@ -0,0 +1,23 @@
+package dialect
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/SQL-Online-Judge/backend/internal/pkg/sqlparse"
+)
+
+func TestRegistry(t *testing.T) {
+	want := []string{MySQL, OpenGauss, SQLite}
+	if got := Names(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Names() = %v, want %v", got, want)
+	}
+
+	opts, ok := Options(OpenGauss)
+	if !ok || opts != sqlparse.PostgreSQLOptions {
+		t.Errorf("Options(%q) = %+v, %v, want the PostgreSQL options", OpenGauss, opts, ok)
+	}
+	if IsSupported("postgres") {
+		t.Errorf("IsSupported(%q) = true for an unregistered dialect", "postgres")
+	}
+}
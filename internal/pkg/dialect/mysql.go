@@ -0,0 +1,9 @@
+package dialect
+
+import "github.com/SQL-Online-Judge/backend/internal/pkg/sqlparse"
+
+const MySQL = "mysql"
+
+func init() {
+	Register(MySQL, sqlparse.MySQLOptions)
+}
@@ -0,0 +1,9 @@
+package dialect
+
+import "github.com/SQL-Online-Judge/backend/internal/pkg/sqlparse"
+
+const OpenGauss = "opengauss"
+
+func init() {
+	Register(OpenGauss, sqlparse.PostgreSQLOptions)
+}
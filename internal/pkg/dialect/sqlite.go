@@ -0,0 +1,9 @@
+package dialect
+
+import "github.com/SQL-Online-Judge/backend/internal/pkg/sqlparse"
+
+const SQLite = "sqlite"
+
+func init() {
+	Register(SQLite, sqlparse.SQLiteOptions)
+}
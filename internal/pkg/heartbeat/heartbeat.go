@@ -0,0 +1,112 @@
+// Package heartbeat keeps a registry of the live judgers in Redis. Each
+// judger refreshes its entry periodically; an entry that is not refreshed
+// expires, so the registry only lists judgers that are alive.
+package heartbeat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const keyPrefix = "judger:"
+
+type Judger struct {
+	Name     string    `json:"name"`
+	DBNames  []string  `json:"dbNames"`
+	Version  string    `json:"version"`
+	InFlight int64     `json:"inFlight"`
+	LastSeen time.Time `json:"lastSeen"`
+}
+
+type Registry struct {
+	rdb *redis.Client
+}
+
+func NewRegistry(rdb *redis.Client) *Registry {
+	return &Registry{
+		rdb: rdb,
+	}
+}
+
+// Beat registers j as alive for ttl.
+func (r *Registry) Beat(j *Judger, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	data, err := json.Marshal(j)
+	if err != nil {
+		return fmt.Errorf("failed to marshal judger: %w", err)
+	}
+
+	err = r.rdb.Set(ctx, keyPrefix+j.Name, data, ttl).Err()
+	if err != nil {
+		return fmt.Errorf("failed to register judger: %w", err)
+	}
+
+	return nil
+}
+
+// Unregister removes the judger name from the registry at once, instead of
+// when its entry expires.
+func (r *Registry) Unregister(name string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := r.rdb.Del(ctx, keyPrefix+name).Err()
+	if err != nil {
+		return fmt.Errorf("failed to unregister judger: %w", err)
+	}
+
+	return nil
+}
+
+// List returns the live judgers sorted by name.
+func (r *Registry) List() ([]*Judger, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var keys []string
+	iter := r.rdb.Scan(ctx, 0, keyPrefix+"*", 100).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	if err := iter.Err(); err != nil {
+		return nil, fmt.Errorf("failed to scan judgers: %w", err)
+	}
+
+	judgers := []*Judger{}
+	if len(keys) == 0 {
+		return judgers, nil
+	}
+
+	values, err := r.rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get judgers: %w", err)
+	}
+
+	for _, value := range values {
+		// the entry expired between SCAN and MGET
+		data, ok := value.(string)
+		if !ok {
+			continue
+		}
+
+		var j Judger
+		err := json.Unmarshal([]byte(data), &j)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal judger: %w", err)
+		}
+		judgers = append(judgers, &j)
+	}
+
+	sort.Slice(judgers, func(i, k int) bool {
+		return judgers[i].Name < judgers[k].Name
+	})
+
+	return judgers, nil
+}
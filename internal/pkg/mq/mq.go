@@ -1,7 +1,10 @@
 package mq
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"time"
 
 	"github.com/SQL-Online-Judge/backend/internal/pkg/logger"
 	"go.uber.org/zap"
@@ -12,19 +15,112 @@ var (
 	ErrMessageEmpty   = fmt.Errorf("message is empty")
 	ErrQueueNotFound  = fmt.Errorf("queue not found")
 	ErrQueueExists    = fmt.Errorf("queue already exists")
+	ErrNoReply        = fmt.Errorf("no reply")
 )
 
 type Msg struct {
 	ID   string
 	Data string
-	Ack  func() error
+	// Deliveries counts how many times the message has been delivered,
+	// including this time.
+	Deliveries int64
+	Ack        func() error
+	// Touch resets the idle time of the message, so that it is not
+	// reclaimed while it is still being handled.
+	Touch func() error
 }
 
 type MQ interface {
 	IsQueueExists(queueName string) (bool, error)
 	CreateQueue(queueName string) error
+	// Enqueue adds msg to queueName with PriorityNormal.
 	Enqueue(queueName, msg string) error
+	EnqueueWithPriority(queueName, msg string, priority Priority) error
+	// Dequeue and Reclaim return the message of the highest priority
+	// available.
 	Dequeue(queueName string, args map[string]interface{}) (*Msg, error)
+	Reclaim(queueName string, args map[string]interface{}) (*Msg, error)
+	DeadLetter(queueName string, msg *Msg) error
+	// Reply sends msg to the reply channel replyTo, which is discarded after
+	// ttl if no one waits on it.
+	Reply(replyTo, msg string, ttl time.Duration) error
+	// AwaitReply waits up to timeout for a reply on replyTo.
+	AwaitReply(replyTo string, timeout time.Duration) (string, error)
+	// Publish sends msg to every current subscriber of channel; no one
+	// else ever receives it.
+	Publish(channel, msg string) error
+	// Subscribe returns the messages published to channel from now on,
+	// until ctx is done.
+	Subscribe(ctx context.Context, channel string) (<-chan string, error)
+	// Stats returns the stats of each lane of queueName.
+	Stats(queueName string) ([]*QueueStats, error)
+	// DeleteConsumer removes consumerName from lane unless messages are
+	// pending for it. A live consumer is added back as soon as it reads
+	// again.
+	DeleteConsumer(lane, consumerName string) error
+}
+
+// Priority orders the messages of a queue: a message is delivered only when
+// there is none of a higher priority. The zero value is PriorityNormal.
+type Priority int
+
+const (
+	PriorityNormal Priority = iota
+	PriorityHigh
+	PriorityLow
+)
+
+// Priorities lists the priorities from the highest to the lowest.
+var Priorities = []Priority{PriorityHigh, PriorityNormal, PriorityLow}
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityHigh:
+		return "high"
+	case PriorityLow:
+		return "low"
+	default:
+		return "normal"
+	}
+}
+
+// Lane returns the stream holding the messages of queueName with priority.
+// The normal lane is the queue itself, so it holds every message enqueued
+// before lanes existed.
+func Lane(queueName string, priority Priority) string {
+	if priority == PriorityHigh || priority == PriorityLow {
+		return queueName + "_" + priority.String()
+	}
+	return queueName
+}
+
+// Lanes returns the lanes of queueName from the highest priority to the
+// lowest.
+func Lanes(queueName string) []string {
+	lanes := make([]string, 0, len(Priorities))
+	for _, priority := range Priorities {
+		lanes = append(lanes, Lane(queueName, priority))
+	}
+	return lanes
+}
+
+// QueueStats tells how far the consumers of a lane are behind.
+type QueueStats struct {
+	Name     string
+	Priority Priority
+	Length   int64
+	// Pending counts the messages delivered but not acked yet.
+	Pending int64
+	// Lag counts the messages not delivered yet.
+	Lag       int64
+	Consumers []*ConsumerStats
+}
+
+type ConsumerStats struct {
+	Name    string
+	Pending int64
+	// Idle is how long ago the consumer last read from the queue.
+	Idle time.Duration
 }
 
 const (
@@ -32,8 +128,21 @@ const (
 	QueueAnswerOutput   = "answer_output"
 	QueueSubmission     = "submission"
 	QueueJudgeResult    = "judge_result"
+	QueueRun            = "run"
+	QueueSimilarity     = "similarity"
 )
 
+// DeadLetterQueue returns the queue holding the messages of queueName that
+// failed too many times.
+func DeadLetterQueue(queueName string) string {
+	return queueName + "_dead_letter"
+}
+
+// ReplyChannel returns the reply channel of the request id.
+func ReplyChannel(id string) string {
+	return "reply:" + id
+}
+
 type Service struct {
 	mq MQ
 }
@@ -89,8 +198,21 @@ func (ms *Service) Enqueue(queueName, msg string) error {
 	return nil
 }
 
+func (ms *Service) EnqueueWithPriority(queueName, msg string, priority Priority) error {
+	err := ms.mq.EnqueueWithPriority(queueName, msg, priority)
+	if err != nil {
+		logger.Logger.Error("failed to enqueue message", zap.String("priority", priority.String()), zap.Error(err))
+		return fmt.Errorf("failed to enqueue message: %w", err)
+	}
+
+	return nil
+}
+
 func (ms *Service) Dequeue(queueName string, args map[string]interface{}) (*Msg, error) {
 	msg, err := ms.mq.Dequeue(queueName, args)
+	if errors.Is(err, ErrNoMessageToDequeue) {
+		return nil, err
+	}
 	if err != nil {
 		logger.Logger.Error("failed to dequeue message", zap.Error(err))
 		return nil, fmt.Errorf("failed to dequeue message: %w", err)
@@ -98,3 +220,90 @@ func (ms *Service) Dequeue(queueName string, args map[string]interface{}) (*Msg,
 
 	return msg, nil
 }
+
+func (ms *Service) Reclaim(queueName string, args map[string]interface{}) (*Msg, error) {
+	msg, err := ms.mq.Reclaim(queueName, args)
+	if errors.Is(err, ErrNoMessageToDequeue) {
+		return nil, err
+	}
+	if err != nil {
+		logger.Logger.Error("failed to reclaim message", zap.Error(err))
+		return nil, fmt.Errorf("failed to reclaim message: %w", err)
+	}
+
+	return msg, nil
+}
+
+func (ms *Service) DeadLetter(queueName string, msg *Msg) error {
+	err := ms.mq.DeadLetter(queueName, msg)
+	if err != nil {
+		logger.Logger.Error("failed to dead-letter message", zap.Error(err))
+		return fmt.Errorf("failed to dead-letter message: %w", err)
+	}
+
+	logger.Logger.Warn("message is dead-lettered", zap.String("queue", queueName), zap.String("msgID", msg.ID), zap.Int64("deliveries", msg.Deliveries))
+	return nil
+}
+
+func (ms *Service) Reply(replyTo, msg string, ttl time.Duration) error {
+	err := ms.mq.Reply(replyTo, msg, ttl)
+	if err != nil {
+		logger.Logger.Error("failed to reply", zap.String("replyTo", replyTo), zap.Error(err))
+		return fmt.Errorf("failed to reply: %w", err)
+	}
+
+	return nil
+}
+
+func (ms *Service) AwaitReply(replyTo string, timeout time.Duration) (string, error) {
+	msg, err := ms.mq.AwaitReply(replyTo, timeout)
+	if errors.Is(err, ErrNoReply) {
+		return "", err
+	}
+	if err != nil {
+		logger.Logger.Error("failed to await reply", zap.String("replyTo", replyTo), zap.Error(err))
+		return "", fmt.Errorf("failed to await reply: %w", err)
+	}
+
+	return msg, nil
+}
+
+func (ms *Service) Publish(channel, msg string) error {
+	err := ms.mq.Publish(channel, msg)
+	if err != nil {
+		logger.Logger.Error("failed to publish", zap.String("channel", channel), zap.Error(err))
+		return fmt.Errorf("failed to publish: %w", err)
+	}
+
+	return nil
+}
+
+func (ms *Service) Subscribe(ctx context.Context, channel string) (<-chan string, error) {
+	msgs, err := ms.mq.Subscribe(ctx, channel)
+	if err != nil {
+		logger.Logger.Error("failed to subscribe", zap.String("channel", channel), zap.Error(err))
+		return nil, fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	return msgs, nil
+}
+
+func (ms *Service) Stats(queueName string) ([]*QueueStats, error) {
+	stats, err := ms.mq.Stats(queueName)
+	if err != nil {
+		logger.Logger.Error("failed to get queue stats", zap.String("queue", queueName), zap.Error(err))
+		return nil, fmt.Errorf("failed to get queue stats: %w", err)
+	}
+
+	return stats, nil
+}
+
+func (ms *Service) DeleteConsumer(lane, consumerName string) error {
+	err := ms.mq.DeleteConsumer(lane, consumerName)
+	if err != nil {
+		logger.Logger.Error("failed to delete consumer", zap.String("lane", lane), zap.String("consumer", consumerName), zap.Error(err))
+		return fmt.Errorf("failed to delete consumer: %w", err)
+	}
+
+	return nil
+}
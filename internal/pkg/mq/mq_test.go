@@ -0,0 +1,171 @@
+package mq
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestLanes(t *testing.T) {
+	tests := []struct {
+		priority Priority
+		want     string
+	}{
+		{PriorityHigh, "submission_high"},
+		{PriorityNormal, "submission"},
+		{PriorityLow, "submission_low"},
+		{Priority(42), "submission"},
+	}
+	for _, tt := range tests {
+		if got := Lane(QueueSubmission, tt.priority); got != tt.want {
+			t.Errorf("Lane(%q, %v) = %q, want %q", QueueSubmission, tt.priority, got, tt.want)
+		}
+	}
+
+	want := []string{"submission_high", "submission", "submission_low"}
+	if got := Lanes(QueueSubmission); !reflect.DeepEqual(got, want) {
+		t.Errorf("Lanes(%q) = %v, want %v", QueueSubmission, got, want)
+	}
+}
+
+// fakeLanes holds the messages of each lane and records the calls made by
+// dequeue.
+type fakeLanes struct {
+	msgs  map[string][]string
+	reads []string
+	waits int
+	// notify is the lane a message is enqueued to while waiting: none if
+	// empty, and none but a notification anyway if taken is set, as if
+	// another consumer dequeued it first
+	notify string
+	taken  bool
+}
+
+func (f *fakeLanes) read(lane string) (*Msg, error) {
+	f.reads = append(f.reads, lane)
+	if len(f.msgs[lane]) == 0 {
+		return nil, ErrNoMessageToDequeue
+	}
+	msg := &Msg{ID: f.msgs[lane][0], Deliveries: 1}
+	f.msgs[lane] = f.msgs[lane][1:]
+	return msg, nil
+}
+
+func (f *fakeLanes) wait(block time.Duration) (bool, error) {
+	f.waits++
+	if f.notify == "" {
+		return false, nil
+	}
+	if !f.taken {
+		f.msgs[f.notify] = append(f.msgs[f.notify], "notified")
+	}
+	f.notify = ""
+	return true, nil
+}
+
+func TestDequeue(t *testing.T) {
+	lanes := Lanes(QueueSubmission)
+
+	tests := []struct {
+		name      string
+		msgs      map[string][]string
+		notify    string
+		taken     bool
+		block     time.Duration
+		want      string
+		wantReads []string
+		wantWaits int
+	}{
+		{
+			name:      "highest lane first",
+			msgs:      map[string][]string{"submission": {"n1"}, "submission_high": {"h1", "h2"}},
+			block:     time.Second,
+			want:      "h1",
+			wantReads: []string{"submission_high"},
+		},
+		{
+			name:      "lowest lane",
+			msgs:      map[string][]string{"submission_low": {"l1"}},
+			block:     time.Second,
+			want:      "l1",
+			wantReads: lanes,
+		},
+		{
+			name:      "empty without blocking",
+			block:     -1,
+			wantReads: lanes,
+		},
+		{
+			name:      "empty after blocking",
+			block:     time.Second,
+			wantReads: lanes,
+			wantWaits: 1,
+		},
+		{
+			name:      "notified",
+			notify:    "submission_low",
+			block:     time.Second,
+			want:      "notified",
+			wantReads: append(append([]string{}, lanes...), lanes...),
+			wantWaits: 1,
+		},
+		{
+			name:      "notified but taken",
+			notify:    "submission",
+			taken:     true,
+			block:     time.Second,
+			wantReads: append(append([]string{}, lanes...), lanes...),
+			wantWaits: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msgs := tt.msgs
+			if msgs == nil {
+				msgs = make(map[string][]string)
+			}
+			f := &fakeLanes{msgs: msgs, notify: tt.notify, taken: tt.taken}
+
+			msg, err := dequeue(lanes, tt.block, f.read, f.wait)
+			switch {
+			case tt.want == "" && !errors.Is(err, ErrNoMessageToDequeue):
+				t.Errorf("dequeue() = %v, %v, want ErrNoMessageToDequeue", msg, err)
+			case tt.want != "" && (err != nil || msg.ID != tt.want):
+				t.Errorf("dequeue() = %v, %v, want %q", msg, err, tt.want)
+			}
+			if !reflect.DeepEqual(f.reads, tt.wantReads) {
+				t.Errorf("read %v, want %v", f.reads, tt.wantReads)
+			}
+			if f.waits != tt.wantWaits {
+				t.Errorf("waited %d times, want %d", f.waits, tt.wantWaits)
+			}
+		})
+	}
+}
+
+func TestDequeueOrder(t *testing.T) {
+	f := &fakeLanes{msgs: map[string][]string{
+		"submission":      {"n1", "n2"},
+		"submission_high": {"h1"},
+		"submission_low":  {"l1"},
+	}}
+
+	var got []string
+	for {
+		msg, err := dequeue(Lanes(QueueSubmission), -1, f.read, f.wait)
+		if errors.Is(err, ErrNoMessageToDequeue) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("dequeue() error = %v", err)
+		}
+		got = append(got, msg.ID)
+	}
+
+	want := []string{"h1", "n1", "n2", "l1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("dequeued %v, want %v", got, want)
+	}
+}
@@ -2,13 +2,21 @@ package mq
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
 )
 
-const groupName = "sqloj"
+const (
+	groupName = "sqloj"
+	// notifyBacklog is the most notifications kept for a queue; a consumer
+	// waking up to an empty queue just waits again
+	notifyBacklog = 1024
+)
 
 var (
 	ErrConsumerNameNotProvided = fmt.Errorf("consumer name is not provided")
@@ -16,52 +24,78 @@ var (
 	ErrNoMessageToDequeue      = fmt.Errorf("no message to dequeue")
 	ErrDataFieldNotFound       = fmt.Errorf("data field not found")
 	ErrDataFieldNotString      = fmt.Errorf("data field is not a string")
+	ErrBlockNotDuration        = fmt.Errorf("block is not a time.Duration")
+	ErrMinIdleNotProvided      = fmt.Errorf("min idle is not provided")
+	ErrMinIdleNotDuration      = fmt.Errorf("min idle is not a time.Duration")
 )
 
 var Redis *RedisMQ
 
 type RedisMQ struct {
 	rdb *redis.Client
+
+	mu           sync.Mutex
+	claimCursors map[string]string
 }
 
-func NewRedisMQ(redis *redis.Client) *RedisMQ {
+func NewRedisMQ(rdb *redis.Client) *RedisMQ {
 	return &RedisMQ{
-		rdb: redis,
+		rdb:          rdb,
+		claimCursors: make(map[string]string),
 	}
 }
 
+// notifyKey returns the list notified of every message enqueued to
+// queueName, which blocked consumers wait on.
+func notifyKey(queueName string) string {
+	return queueName + "_notify"
+}
+
+// IsQueueExists reports whether every lane of queueName exists.
 func (r *RedisMQ) IsQueueExists(queueName string) (bool, error) {
 	ctx, cancle := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancle()
 
-	val, err := r.rdb.Exists(ctx, queueName).Result()
+	lanes := Lanes(queueName)
+	val, err := r.rdb.Exists(ctx, lanes...).Result()
 	if err != nil {
 		return false, fmt.Errorf("failed to check if queue exists: %w", err)
 	}
 
-	return val == 1, nil
+	return val == int64(len(lanes)), nil
 }
 
+// CreateQueue creates the lanes of queueName that do not exist yet.
 func (r *RedisMQ) CreateQueue(queueName string) error {
 	ctx, cancle := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancle()
 
-	err := r.rdb.XGroupCreateMkStream(ctx, queueName, groupName, "0").Err()
-	if err != nil {
-		return fmt.Errorf("failed to create queue: %w", err)
+	for _, lane := range Lanes(queueName) {
+		err := r.rdb.XGroupCreateMkStream(ctx, lane, groupName, "0").Err()
+		if err != nil && !strings.HasPrefix(err.Error(), "BUSYGROUP") {
+			return fmt.Errorf("failed to create queue: %w", err)
+		}
 	}
 
 	return nil
 }
 
 func (r *RedisMQ) Enqueue(queueName, msg string) error {
+	return r.EnqueueWithPriority(queueName, msg, PriorityNormal)
+}
+
+func (r *RedisMQ) EnqueueWithPriority(queueName, msg string, priority Priority) error {
 	ctx, cancle := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancle()
 
-	_, err := r.rdb.XAdd(ctx, &redis.XAddArgs{
-		Stream: queueName,
+	pipe := r.rdb.TxPipeline()
+	pipe.XAdd(ctx, &redis.XAddArgs{
+		Stream: Lane(queueName, priority),
 		Values: map[string]interface{}{"data": msg},
-	}).Result()
+	})
+	pipe.LPush(ctx, notifyKey(queueName), 1)
+	pipe.LTrim(ctx, notifyKey(queueName), 0, notifyBacklog-1)
+	_, err := pipe.Exec(ctx)
 	if err != nil {
 		return fmt.Errorf("failed to enqueue message: %w", err)
 	}
@@ -69,9 +103,10 @@ func (r *RedisMQ) Enqueue(queueName, msg string) error {
 	return nil
 }
 
+// Dequeue reads the lanes of queueName one by one from the highest
+// priority, one message at a time. When all of them are empty it waits up to
+// args["block"] for a message to be enqueued and reads them once more.
 func (r *RedisMQ) Dequeue(queueName string, args map[string]interface{}) (*Msg, error) {
-	ctx := context.Background()
-
 	iConsumerName, ok := args["consumerName"]
 	if !ok {
 		return nil, fmt.Errorf("%w", ErrConsumerNameNotProvided)
@@ -81,13 +116,67 @@ func (r *RedisMQ) Dequeue(queueName string, args map[string]interface{}) (*Msg,
 		return nil, fmt.Errorf("%w", ErrConsumerNameNotString)
 	}
 
+	block := time.Duration(-1)
+	if iBlock, ok := args["block"]; ok {
+		block, ok = iBlock.(time.Duration)
+		if !ok {
+			return nil, fmt.Errorf("%w", ErrBlockNotDuration)
+		}
+	}
+
+	read := func(lane string) (*Msg, error) {
+		return r.readLane(lane, consumerName)
+	}
+	wait := func(block time.Duration) (bool, error) {
+		return r.awaitNotify(queueName, block)
+	}
+	return dequeue(Lanes(queueName), block, read, wait)
+}
+
+// dequeue returns the first message read from lanes, in order. When there
+// is none and block is not negative, it waits for a notification up to
+// block and tries once more.
+func dequeue(lanes []string, block time.Duration, read func(lane string) (*Msg, error), wait func(block time.Duration) (bool, error)) (*Msg, error) {
+	for {
+		for _, lane := range lanes {
+			msg, err := read(lane)
+			if errors.Is(err, ErrNoMessageToDequeue) {
+				continue
+			}
+			return msg, err
+		}
+
+		if block < 0 {
+			return nil, fmt.Errorf("%w", ErrNoMessageToDequeue)
+		}
+
+		notified, err := wait(block)
+		if err != nil {
+			return nil, err
+		}
+		if !notified {
+			return nil, fmt.Errorf("%w", ErrNoMessageToDequeue)
+		}
+		block = -1
+	}
+}
+
+// readLane delivers the next message of lane to consumerName without
+// blocking.
+func (r *RedisMQ) readLane(lane, consumerName string) (*Msg, error) {
+	ctx, cancle := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancle()
+
 	res, err := r.rdb.XReadGroup(ctx, &redis.XReadGroupArgs{
 		Group:    groupName,
 		Consumer: consumerName,
-		Streams:  []string{queueName, ">"},
+		Streams:  []string{lane, ">"},
 		Block:    -1,
 		Count:    1,
 	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("%w", ErrNoMessageToDequeue)
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to dequeue message: %w", err)
 	}
@@ -95,24 +184,333 @@ func (r *RedisMQ) Dequeue(queueName string, args map[string]interface{}) (*Msg,
 	if len(res) == 0 || len(res[0].Messages) == 0 {
 		return nil, fmt.Errorf("%w", ErrNoMessageToDequeue)
 	}
+	return r.toMsg(lane, consumerName, res[0].Messages[0], 1)
+}
+
+// awaitNotify waits up to block for a message to be enqueued to
+// queueName, forever if block is 0. It reports whether one was.
+func (r *RedisMQ) awaitNotify(queueName string, block time.Duration) (bool, error) {
+	err := r.rdb.BLPop(context.Background(), block, notifyKey(queueName)).Err()
+	if errors.Is(err, redis.Nil) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to wait for message: %w", err)
+	}
+	return true, nil
+}
+
+// Reclaim takes over a message that was delivered to a consumer but not
+// acked for at least args["minIdle"], e.g. because the consumer crashed.
+// The lanes of queueName are searched from the highest priority.
+func (r *RedisMQ) Reclaim(queueName string, args map[string]interface{}) (*Msg, error) {
+	iConsumerName, ok := args["consumerName"]
+	if !ok {
+		return nil, fmt.Errorf("%w", ErrConsumerNameNotProvided)
+	}
+	consumerName, ok := iConsumerName.(string)
+	if !ok {
+		return nil, fmt.Errorf("%w", ErrConsumerNameNotString)
+	}
+
+	iMinIdle, ok := args["minIdle"]
+	if !ok {
+		return nil, fmt.Errorf("%w", ErrMinIdleNotProvided)
+	}
+	minIdle, ok := iMinIdle.(time.Duration)
+	if !ok {
+		return nil, fmt.Errorf("%w", ErrMinIdleNotDuration)
+	}
+
+	for _, lane := range Lanes(queueName) {
+		msg, err := r.reclaim(lane, consumerName, minIdle)
+		if errors.Is(err, ErrNoMessageToDequeue) {
+			continue
+		}
+		return msg, err
+	}
+
+	return nil, fmt.Errorf("%w", ErrNoMessageToDequeue)
+}
+
+func (r *RedisMQ) reclaim(lane, consumerName string, minIdle time.Duration) (*Msg, error) {
+	ctx, cancle := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancle()
+
+	r.mu.Lock()
+	start, ok := r.claimCursors[lane]
+	if !ok {
+		start = "0-0"
+	}
+	r.mu.Unlock()
+
+	msgs, next, err := r.rdb.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   lane,
+		Group:    groupName,
+		MinIdle:  minIdle,
+		Start:    start,
+		Count:    1,
+		Consumer: consumerName,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to reclaim message: %w", err)
+	}
+
+	r.mu.Lock()
+	r.claimCursors[lane] = next
+	r.mu.Unlock()
+
+	if len(msgs) == 0 {
+		return nil, fmt.Errorf("%w", ErrNoMessageToDequeue)
+	}
+
+	msg := msgs[0]
+	pending, err := r.rdb.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream: lane,
+		Group:  groupName,
+		Start:  msg.ID,
+		End:    msg.ID,
+		Count:  1,
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get delivery count: %w", err)
+	}
+
+	var deliveries int64 = 1
+	if len(pending) > 0 {
+		deliveries = pending[0].RetryCount
+	}
+
+	return r.toMsg(lane, consumerName, msg, deliveries)
+}
+
+// DeadLetter moves msg to the dead-letter stream of queueName and acks it.
+func (r *RedisMQ) DeadLetter(queueName string, msg *Msg) error {
+	ctx, cancle := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancle()
+
+	_, err := r.rdb.XAdd(ctx, &redis.XAddArgs{
+		Stream: DeadLetterQueue(queueName),
+		Values: map[string]interface{}{
+			"data":       msg.Data,
+			"originID":   msg.ID,
+			"deliveries": msg.Deliveries,
+		},
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("failed to add message to dead-letter queue: %w", err)
+	}
+
+	return msg.Ack()
+}
+
+// Reply pushes msg to the list replyTo. The list expires after ttl, so a
+// reply no one waits for any longer does not stay around.
+func (r *RedisMQ) Reply(replyTo, msg string, ttl time.Duration) error {
+	ctx, cancle := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancle()
+
+	pipe := r.rdb.TxPipeline()
+	pipe.RPush(ctx, replyTo, msg)
+	pipe.Expire(ctx, replyTo, ttl)
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to push reply: %w", err)
+	}
+
+	return nil
+}
+
+func (r *RedisMQ) AwaitReply(replyTo string, timeout time.Duration) (string, error) {
+	res, err := r.rdb.BLPop(context.Background(), timeout, replyTo).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", fmt.Errorf("%w", ErrNoReply)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to pop reply: %w", err)
+	}
+
+	// res holds the key and the value
+	return res[1], nil
+}
+
+func (r *RedisMQ) Publish(channel, msg string) error {
+	ctx, cancle := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancle()
+
+	err := r.rdb.Publish(ctx, channel, msg).Err()
+	if err != nil {
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
 
-	id := res[0].Messages[0].ID
+	return nil
+}
 
-	iData, ok := res[0].Messages[0].Values["data"]
+// Subscribe holds a connection of its own until ctx is done, when the
+// returned channel is closed.
+func (r *RedisMQ) Subscribe(ctx context.Context, channel string) (<-chan string, error) {
+	pubsub := r.rdb.Subscribe(ctx, channel)
+	// wait for the confirmation, so nothing published after Subscribe
+	// returns is missed
+	_, err := pubsub.Receive(ctx)
+	if err != nil {
+		pubsub.Close()
+		return nil, fmt.Errorf("failed to subscribe: %w", err)
+	}
+
+	msgs := make(chan string)
+	go func() {
+		defer close(msgs)
+		defer pubsub.Close()
+
+		received := pubsub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case m, ok := <-received:
+				if !ok {
+					return
+				}
+				select {
+				case msgs <- m.Payload:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return msgs, nil
+}
+
+func (r *RedisMQ) Stats(queueName string) ([]*QueueStats, error) {
+	var stats []*QueueStats
+	for _, priority := range Priorities {
+		laneStats, err := r.laneStats(Lane(queueName, priority))
+		if err != nil {
+			return nil, err
+		}
+		laneStats.Priority = priority
+		stats = append(stats, laneStats)
+	}
+
+	return stats, nil
+}
+
+func (r *RedisMQ) laneStats(lane string) (*QueueStats, error) {
+	ctx, cancle := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancle()
+
+	length, err := r.rdb.XLen(ctx, lane).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get queue length: %w", err)
+	}
+
+	stats := &QueueStats{Name: lane, Length: length}
+
+	groups, err := r.rdb.XInfoGroups(ctx, lane).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get queue groups: %w", err)
+	}
+	for _, group := range groups {
+		if group.Name == groupName {
+			stats.Pending = group.Pending
+			stats.Lag = group.Lag
+		}
+	}
+
+	consumers, err := r.rdb.XInfoConsumers(ctx, lane, groupName).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get queue consumers: %w", err)
+	}
+	for _, consumer := range consumers {
+		stats.Consumers = append(stats.Consumers, &ConsumerStats{
+			Name:    consumer.Name,
+			Pending: consumer.Pending,
+			Idle:    consumer.Idle,
+		})
+	}
+
+	return stats, nil
+}
+
+// deleteConsumerScript checks the pending messages and deletes the consumer
+// at once, so that no message is delivered to it in between and lost.
+var deleteConsumerScript = redis.NewScript(`
+if #redis.call('XPENDING', KEYS[1], ARGV[1], '-', '+', 1, ARGV[2]) > 0 then
+	return 0
+end
+return redis.call('XGROUP', 'DELCONSUMER', KEYS[1], ARGV[1], ARGV[2])
+`)
+
+func (r *RedisMQ) DeleteConsumer(lane, consumerName string) error {
+	ctx, cancle := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancle()
+
+	err := deleteConsumerScript.Run(ctx, r.rdb, []string{lane}, groupName, consumerName).Err()
+	if err != nil {
+		return fmt.Errorf("failed to delete consumer: %w", err)
+	}
+	return nil
+}
+
+func (r *RedisMQ) ack(queueName, id string) error {
+	ctx, cancle := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancle()
+
+	err := r.rdb.XAck(ctx, queueName, groupName, id).Err()
+	if err != nil {
+		return fmt.Errorf("failed to ack message: %w", err)
+	}
+	return nil
+}
+
+// touch claims the message id of queueName for consumerName again, which
+// resets its idle time. JUSTID leaves its delivery count as it is.
+func (r *RedisMQ) touch(queueName, consumerName, id string) error {
+	ctx, cancle := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancle()
+
+	err := r.rdb.XClaimJustID(ctx, &redis.XClaimArgs{
+		Stream:   queueName,
+		Group:    groupName,
+		Consumer: consumerName,
+		Messages: []string{id},
+	}).Err()
+	if err != nil {
+		return fmt.Errorf("failed to touch message: %w", err)
+	}
+	return nil
+}
+
+// toMsg converts a stream entry delivered to consumerName to a Msg. An
+// entry without a valid data field can never be handled, so it is acked and
+// dropped.
+func (r *RedisMQ) toMsg(queueName, consumerName string, xmsg redis.XMessage, deliveries int64) (*Msg, error) {
+	id := xmsg.ID
+
+	iData, ok := xmsg.Values["data"]
 	if !ok {
+		r.ack(queueName, id)
 		return nil, fmt.Errorf("%w", ErrDataFieldNotFound)
 	}
 
 	data, ok := iData.(string)
 	if !ok {
+		r.ack(queueName, id)
 		return nil, fmt.Errorf("%w", ErrDataFieldNotString)
 	}
 
 	return &Msg{
-		ID:   id,
-		Data: data,
+		ID:         id,
+		Data:       data,
+		Deliveries: deliveries,
 		Ack: func() error {
-			return fmt.Errorf("failed to ack message: %w", r.rdb.XAck(ctx, queueName, groupName, id).Err())
+			return r.ack(queueName, id)
+		},
+		Touch: func() error {
+			return r.touch(queueName, consumerName, id)
 		},
 	}, nil
 }
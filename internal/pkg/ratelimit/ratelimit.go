@@ -0,0 +1,47 @@
+// Package ratelimit counts events in Redis, so that every core process
+// shares the same limits.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// Limiter allows at most limit events per key in each fixed window.
+type Limiter struct {
+	rdb    *redis.Client
+	prefix string
+	limit  int64
+	window time.Duration
+}
+
+func NewLimiter(rdb *redis.Client, prefix string, limit int64, window time.Duration) *Limiter {
+	return &Limiter{
+		rdb:    rdb,
+		prefix: prefix,
+		limit:  limit,
+		window: window,
+	}
+}
+
+// Allow counts an event of key and reports whether it is within the limit.
+func (l *Limiter) Allow(key string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	k := l.prefix + ":" + key
+
+	// the counter is created with the window as its ttl, INCR keeps the ttl
+	pipe := l.rdb.TxPipeline()
+	pipe.SetNX(ctx, k, 0, l.window)
+	count := pipe.Incr(ctx, k)
+	_, err := pipe.Exec(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to count event: %w", err)
+	}
+
+	return count.Val() <= l.limit, nil
+}
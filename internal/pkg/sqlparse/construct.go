@@ -0,0 +1,222 @@
+package sqlparse
+
+// Constructs are the features of SQL a problem may require or forbid.
+const (
+	ConstructJoin           = "join"
+	ConstructSubquery       = "subquery"
+	ConstructCTE            = "cte"
+	ConstructGroupBy        = "group_by"
+	ConstructHaving         = "having"
+	ConstructOrderBy        = "order_by"
+	ConstructLimit          = "limit"
+	ConstructDistinct       = "distinct"
+	ConstructAggregate      = "aggregate"
+	ConstructWindowFunction = "window_function"
+	ConstructUnion          = "union"
+	ConstructIntersect      = "intersect"
+	ConstructExcept         = "except"
+	ConstructCase           = "case"
+	ConstructExists         = "exists"
+)
+
+var constructs = map[string]bool{
+	ConstructJoin:           true,
+	ConstructSubquery:       true,
+	ConstructCTE:            true,
+	ConstructGroupBy:        true,
+	ConstructHaving:         true,
+	ConstructOrderBy:        true,
+	ConstructLimit:          true,
+	ConstructDistinct:       true,
+	ConstructAggregate:      true,
+	ConstructWindowFunction: true,
+	ConstructUnion:          true,
+	ConstructIntersect:      true,
+	ConstructExcept:         true,
+	ConstructCase:           true,
+	ConstructExists:         true,
+}
+
+func IsConstruct(construct string) bool {
+	return constructs[construct]
+}
+
+var aggregateFunctions = map[string]bool{
+	"COUNT":        true,
+	"SUM":          true,
+	"AVG":          true,
+	"MIN":          true,
+	"MAX":          true,
+	"GROUP_CONCAT": true,
+	"STRING_AGG":   true,
+	"ARRAY_AGG":    true,
+	"BIT_AND":      true,
+	"BIT_OR":       true,
+	"BIT_XOR":      true,
+	"BOOL_AND":     true,
+	"BOOL_OR":      true,
+	"STDDEV":       true,
+	"VARIANCE":     true,
+	"TOTAL":        true,
+}
+
+// queryStarts are the keywords that start a query in parentheses.
+var queryStarts = map[string]bool{
+	"SELECT": true,
+	"WITH":   true,
+	"VALUES": true,
+}
+
+// Constructs returns the set of constructs used by the statement.
+//
+// A parenthesized query counts as a subquery unless it is a whole operand of
+// the statement or of a set operation, or the body of a common table
+// expression. Only explicit JOINs count as joins: "FROM a, b" does not.
+func (s *Statement) Constructs() map[string]bool {
+	used := make(map[string]bool)
+
+	tokens := s.Tokens
+	// the depth of the parentheses and, for each open one, whether it holds
+	// a window specification
+	var windows []bool
+	inWindow := func() bool {
+		for _, w := range windows {
+			if w {
+				return true
+			}
+		}
+		return false
+	}
+
+	for i := range tokens {
+		t := &tokens[i]
+		prev, next, nextNext := tokenAt(tokens, i-1), tokenAt(tokens, i+1), tokenAt(tokens, i+2)
+
+		switch {
+		case t.IsSymbol("("):
+			windows = append(windows, prev.IsWord("OVER"))
+			if next.Kind == Word && queryStarts[next.Upper()] && !isQueryOperand(tokens, i) {
+				used[ConstructSubquery] = true
+			}
+		case t.IsSymbol(")"):
+			if len(windows) > 0 {
+				windows = windows[:len(windows)-1]
+			}
+		case t.Kind != Word:
+		case t.IsWord("JOIN"):
+			used[ConstructJoin] = true
+		case t.IsWord("WITH"):
+			// WITH also appears in e.g. WITH ROLLUP and WITH TIME ZONE, a
+			// common table expression is a name followed by AS or a column list
+			name, after := next, nextNext
+			if next.IsWord("RECURSIVE") {
+				name, after = nextNext, tokenAt(tokens, i+3)
+			}
+			if name.IsName() && (after.IsWord("AS") || after.IsSymbol("(")) {
+				used[ConstructCTE] = true
+			}
+		case t.IsWord("GROUP") && next.IsWord("BY"):
+			used[ConstructGroupBy] = true
+		case t.IsWord("HAVING"):
+			used[ConstructHaving] = true
+		case t.IsWord("ORDER") && next.IsWord("BY") && !inWindow():
+			used[ConstructOrderBy] = true
+		case t.IsWord("LIMIT"), t.IsWord("FETCH") && (next.IsWord("FIRST") || next.IsWord("NEXT")):
+			used[ConstructLimit] = true
+		case t.IsWord("DISTINCT") && !isSetOperator(prev) && !prev.IsWord("IS") && !prev.IsWord("NOT"):
+			used[ConstructDistinct] = true
+		case t.IsWord("OVER") && (next.IsSymbol("(") || next.IsName()):
+			used[ConstructWindowFunction] = true
+		case t.IsWord("UNION"):
+			used[ConstructUnion] = true
+		case t.IsWord("INTERSECT"):
+			used[ConstructIntersect] = true
+		case t.IsWord("EXCEPT"), t.IsWord("MINUS"):
+			used[ConstructExcept] = true
+		case t.IsWord("CASE"):
+			used[ConstructCase] = true
+		case t.IsWord("EXISTS") && next.IsSymbol("("):
+			used[ConstructExists] = true
+		}
+
+		if t.IsName() && next.IsSymbol("(") && aggregateFunctions[t.Upper()] {
+			used[ConstructAggregate] = true
+		}
+	}
+
+	return used
+}
+
+// Constructs returns the set of constructs used by any of the statements.
+func Constructs(statements []*Statement) map[string]bool {
+	used := make(map[string]bool)
+	for _, statement := range statements {
+		for construct := range statement.Constructs() {
+			used[construct] = true
+		}
+	}
+	return used
+}
+
+// isQueryOperand reports whether the parenthesized query opened at i is a
+// whole operand rather than a subquery: it starts the statement, follows a
+// set operator or another opening parenthesis of such an operand, or is the
+// body of a common table expression.
+func isQueryOperand(tokens []Token, i int) bool {
+	for i > 0 && tokens[i-1].IsSymbol("(") {
+		i--
+	}
+	if i == 0 {
+		return true
+	}
+
+	prev := &tokens[i-1]
+	if isSetOperator(prev) || prev.IsWord("ALL") || prev.IsWord("DISTINCT") {
+		return true
+	}
+
+	// name AS ( ... ) and name AS [NOT] MATERIALIZED ( ... )
+	j := i - 1
+	if prev.IsWord("MATERIALIZED") {
+		j--
+		if tokenAt(tokens, j).IsWord("NOT") {
+			j--
+		}
+	}
+	return tokenAt(tokens, j).IsWord("AS") && isInWith(tokens, j)
+}
+
+// isInWith reports whether the token at i belongs to the common table
+// expressions of a WITH at the same depth.
+func isInWith(tokens []Token, i int) bool {
+	depth := 0
+	for ; i >= 0; i-- {
+		t := &tokens[i]
+		switch {
+		case t.IsSymbol(")"):
+			depth++
+		case t.IsSymbol("("):
+			if depth == 0 {
+				return false
+			}
+			depth--
+		case depth == 0 && t.IsWord("WITH"):
+			return true
+		case depth == 0 && t.Kind == Word && mainVerbs[t.Upper()]:
+			return false
+		}
+	}
+	return false
+}
+
+func isSetOperator(t *Token) bool {
+	return t.IsWord("UNION") || t.IsWord("INTERSECT") || t.IsWord("EXCEPT") || t.IsWord("MINUS")
+}
+
+// tokenAt returns the token at i, or an empty token if i is out of range.
+func tokenAt(tokens []Token, i int) *Token {
+	if i < 0 || i >= len(tokens) {
+		return &Token{Kind: Symbol}
+	}
+	return &tokens[i]
+}
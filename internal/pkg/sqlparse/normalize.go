@@ -0,0 +1,44 @@
+package sqlparse
+
+import "strings"
+
+// Normalize returns sql without its comments, trailing semicolons and the
+// white space that cannot change its meaning, so that SQL differing only in
+// layout normalizes alike. Tokens are kept as written, quotes and case
+// included.
+//
+// White space is dropped around commas, semicolons and closing
+// parentheses, after opening parentheses, and before an opening parenthesis
+// that does not follow a name: "COUNT (*)" differs from "COUNT(*)" in MySQL.
+// Anywhere else it is reduced to a single space, since it may separate e.g.
+// "- -" from a "--" comment or "> =" from ">=".
+func Normalize(sql string, opts Options) string {
+	tokens, spans := tokenize(sql, opts)
+	for len(tokens) > 0 && tokens[len(tokens)-1].IsSymbol(";") {
+		tokens = tokens[:len(tokens)-1]
+	}
+
+	var b strings.Builder
+	for i := range tokens {
+		if i > 0 && spans[i].start > spans[i-1].end && isSignificantSpace(&tokens[i-1], &tokens[i]) {
+			b.WriteByte(' ')
+		}
+		b.WriteString(sql[spans[i].start:spans[i].end])
+	}
+	return b.String()
+}
+
+// isSignificantSpace reports whether white space between the tokens prev
+// and next may change the meaning of the SQL.
+func isSignificantSpace(prev, next *Token) bool {
+	switch {
+	case prev.IsSymbol(",") || prev.IsSymbol(";") || prev.IsSymbol("(") || prev.IsSymbol(")"):
+		return false
+	case next.IsSymbol(",") || next.IsSymbol(";") || next.IsSymbol(")"):
+		return false
+	case next.IsSymbol("("):
+		return prev.IsName()
+	default:
+		return true
+	}
+}
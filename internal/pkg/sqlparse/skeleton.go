@@ -0,0 +1,69 @@
+package sqlparse
+
+import "strconv"
+
+// clauseKeywords may follow a table or a column without being its alias.
+var clauseKeywords = map[string]bool{
+	"ALL": true, "AND": true, "ANY": true, "AS": true, "ASC": true,
+	"BETWEEN": true, "BY": true, "CASE": true, "CROSS": true, "DESC": true,
+	"DISTINCT": true, "ELSE": true, "END": true, "ESCAPE": true,
+	"EXCEPT": true, "EXISTS": true, "FETCH": true, "FOR": true, "FROM": true,
+	"FULL": true, "GROUP": true, "HAVING": true, "ILIKE": true, "IN": true,
+	"INNER": true, "INTERSECT": true, "INTO": true, "IS": true, "JOIN": true,
+	"LEFT": true, "LIKE": true, "LIMIT": true, "MINUS": true, "NATURAL": true,
+	"NOT": true, "NULL": true, "NULLS": true, "OFFSET": true, "ON": true,
+	"OR": true, "ORDER": true, "OUTER": true, "OVER": true, "PARTITION": true,
+	"REGEXP": true, "RIGHT": true, "RLIKE": true, "SELECT": true, "SET": true,
+	"SOME": true, "THEN": true, "UNION": true, "USING": true, "VALUES": true,
+	"WHEN": true, "WHERE": true, "WINDOW": true, "WITH": true, "XOR": true,
+}
+
+// Skeleton returns the tokens of sql with what is easily changed to hide a
+// copy taken out: the layout, the case of words, literal values, which all
+// read "?", and alias names, which read "#1", "#2"... in the order they
+// first appear. "AS" before an alias is dropped too.
+//
+// An alias is a name after AS, or a name right after a name, a closing
+// parenthesis or END unless it is a clause keyword. This is a guess without a
+// parser, but it is the same guess for every SQL compared.
+func Skeleton(sql string, opts Options) []string {
+	tokens := Tokenize(sql, opts)
+	for len(tokens) > 0 && tokens[len(tokens)-1].IsSymbol(";") {
+		tokens = tokens[:len(tokens)-1]
+	}
+
+	aliases := make(map[string]bool)
+	for i := 1; i < len(tokens); i++ {
+		t, prev := &tokens[i], &tokens[i-1]
+		if !t.IsName() || t.Kind == Word && clauseKeywords[t.Upper()] {
+			continue
+		}
+		afterName := prev.IsName() && !(prev.Kind == Word && clauseKeywords[prev.Upper()])
+		if prev.IsWord("AS") || afterName || prev.IsSymbol(")") || prev.IsWord("END") {
+			aliases[t.Upper()] = true
+		}
+	}
+
+	placeholders := make(map[string]string)
+	skeleton := make([]string, 0, len(tokens))
+	for i := range tokens {
+		t := &tokens[i]
+		switch {
+		case t.Kind == String || t.Kind == Number:
+			skeleton = append(skeleton, "?")
+		case t.IsWord("AS") && i+1 < len(tokens) && aliases[tokens[i+1].Upper()]:
+		case t.IsName() && aliases[t.Upper()]:
+			placeholder, ok := placeholders[t.Upper()]
+			if !ok {
+				placeholder = "#" + strconv.Itoa(len(placeholders)+1)
+				placeholders[t.Upper()] = placeholder
+			}
+			skeleton = append(skeleton, placeholder)
+		case t.Kind == Symbol:
+			skeleton = append(skeleton, t.Text)
+		default:
+			skeleton = append(skeleton, t.Upper())
+		}
+	}
+	return skeleton
+}
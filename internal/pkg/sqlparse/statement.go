@@ -0,0 +1,97 @@
+package sqlparse
+
+type Statement struct {
+	Tokens []Token
+}
+
+// Split tokenizes sql and splits it into statements at the semicolons.
+// Empty statements are dropped.
+func Split(sql string, opts Options) []*Statement {
+	var statements []*Statement
+
+	var tokens []Token
+	for _, t := range Tokenize(sql, opts) {
+		if t.IsSymbol(";") {
+			if len(tokens) > 0 {
+				statements = append(statements, &Statement{Tokens: tokens})
+			}
+			tokens = nil
+			continue
+		}
+		tokens = append(tokens, t)
+	}
+	if len(tokens) > 0 {
+		statements = append(statements, &Statement{Tokens: tokens})
+	}
+
+	return statements
+}
+
+// mainVerbs are the keywords that may follow the common table expressions
+// of a WITH statement.
+var mainVerbs = map[string]bool{
+	"SELECT":  true,
+	"INSERT":  true,
+	"UPDATE":  true,
+	"DELETE":  true,
+	"REPLACE": true,
+	"MERGE":   true,
+	"VALUES":  true,
+	"TABLE":   true,
+}
+
+// Kind returns the kind of the statement in upper case, e.g. SELECT or
+// DROP. A WITH statement has the kind of its main statement.
+func (s *Statement) Kind() string {
+	i := 0
+	for i < len(s.Tokens) && s.Tokens[i].IsSymbol("(") {
+		i++
+	}
+	if i == len(s.Tokens) || s.Tokens[i].Kind != Word {
+		return ""
+	}
+
+	kind := s.Tokens[i].Upper()
+	if kind != "WITH" {
+		return kind
+	}
+
+	// the main statement is the first verb outside the parentheses holding
+	// the common table expressions
+	depth := 0
+	for _, t := range s.Tokens[i+1:] {
+		switch {
+		case t.IsSymbol("("):
+			depth++
+		case t.IsSymbol(")"):
+			depth--
+		case depth == 0 && t.Kind == Word && mainVerbs[t.Upper()]:
+			return t.Upper()
+		}
+	}
+	return kind
+}
+
+// Functions returns the names called as functions in the statement, i.e.
+// the names followed by an opening parenthesis, in upper case.
+func (s *Statement) Functions() []string {
+	var functions []string
+	for i := 0; i+1 < len(s.Tokens); i++ {
+		if s.Tokens[i].IsName() && s.Tokens[i+1].IsSymbol("(") {
+			functions = append(functions, s.Tokens[i].Upper())
+		}
+	}
+	return functions
+}
+
+// Names returns the words and quoted identifiers of the statement in upper
+// case.
+func (s *Statement) Names() []string {
+	var names []string
+	for _, t := range s.Tokens {
+		if t.IsName() {
+			names = append(names, t.Upper())
+		}
+	}
+	return names
+}
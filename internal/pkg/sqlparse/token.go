@@ -0,0 +1,280 @@
+// Package sqlparse splits SQL into tokens and statements, enough to tell
+// what a submission does without a full parser for every dialect.
+package sqlparse
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type Kind int
+
+const (
+	Word Kind = iota
+	QuotedIdent
+	String
+	Number
+	Symbol
+)
+
+type Token struct {
+	Kind Kind
+	// Text is the token as written, except for QuotedIdent and String whose
+	// Text is the content without quotes.
+	Text string
+}
+
+// Upper returns the text of the token in upper case, the canonical form of
+// keywords.
+func (t *Token) Upper() string {
+	return strings.ToUpper(t.Text)
+}
+
+// IsWord reports whether the token is the keyword word, case-insensitively.
+func (t *Token) IsWord(word string) bool {
+	return t.Kind == Word && strings.EqualFold(t.Text, word)
+}
+
+// IsSymbol reports whether the token is the symbol s.
+func (t *Token) IsSymbol(s string) bool {
+	return t.Kind == Symbol && t.Text == s
+}
+
+// IsName reports whether the token may name something: a word or a quoted
+// identifier.
+func (t *Token) IsName() bool {
+	return t.Kind == Word || t.Kind == QuotedIdent
+}
+
+// Options tell how the dialect quotes and comments. Dialects disagree on
+// it, so to see everything a statement may do in any of them, tokenize with
+// every element of AllOptions.
+type Options struct {
+	// BackslashEscapes makes a backslash escape the next character in
+	// strings and quoted identifiers, as in MySQL.
+	BackslashEscapes bool
+	// DollarQuotes enables $tag$...$tag$ strings, as in PostgreSQL.
+	DollarQuotes bool
+	// EscapeStrings enables E'...' strings, in which a backslash escapes the
+	// next character, as in PostgreSQL.
+	EscapeStrings bool
+	// BracketIdents enables [...] quoted identifiers, as in SQLite.
+	BracketIdents bool
+	// HashComments makes "#" start a line comment, as in MySQL.
+	HashComments bool
+	// SpacedDashComments makes "--" start a line comment only when followed
+	// by white space, as in MySQL.
+	SpacedDashComments bool
+	// NestedComments makes block comments nest, as in PostgreSQL.
+	NestedComments bool
+	// ExecutableComments makes the content of /*! ... */ comments SQL, as in
+	// MySQL, which runs it.
+	ExecutableComments bool
+}
+
+var (
+	MySQLOptions = Options{
+		BackslashEscapes:   true,
+		HashComments:       true,
+		SpacedDashComments: true,
+		ExecutableComments: true,
+	}
+	PostgreSQLOptions = Options{
+		DollarQuotes:   true,
+		EscapeStrings:  true,
+		NestedComments: true,
+	}
+	SQLiteOptions = Options{BracketIdents: true}
+	AllOptions    = []Options{MySQLOptions, PostgreSQLOptions, SQLiteOptions}
+)
+
+// Tokenize splits sql into tokens, dropping white space and comments.
+func Tokenize(sql string, opts Options) []Token {
+	tokens, _ := tokenize(sql, opts)
+	return tokens
+}
+
+// span is the position of a token in the SQL it was read from.
+type span struct {
+	start, end int
+}
+
+// tokenize is Tokenize that also returns the span of each token.
+func tokenize(sql string, opts Options) ([]Token, []span) {
+	var tokens []Token
+	var spans []span
+
+	for i := 0; i < len(sql); {
+		c := sql[i]
+		start, count := i, len(tokens)
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v':
+			i++
+		case strings.HasPrefix(sql[i:], "--") && (!opts.SpacedDashComments || i+2 == len(sql) || isSpace(sql[i+2])),
+			c == '#' && opts.HashComments:
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				i = len(sql)
+			} else {
+				i += end + 1
+			}
+		case strings.HasPrefix(sql[i:], "/*!") && opts.ExecutableComments:
+			// skip the marker and the optional version, keep the content
+			i += 3
+			for i < len(sql) && isDigit(sql[i]) {
+				i++
+			}
+		case strings.HasPrefix(sql[i:], "/*"):
+			i += scanBlockComment(sql[i:], opts.NestedComments)
+		case c == '\'':
+			text, n := scanQuoted(sql[i:], '\'', opts.BackslashEscapes)
+			tokens = append(tokens, Token{Kind: String, Text: text})
+			i += n
+		case (c == 'E' || c == 'e') && opts.EscapeStrings && i+1 < len(sql) && sql[i+1] == '\'':
+			text, n := scanQuoted(sql[i+1:], '\'', true)
+			tokens = append(tokens, Token{Kind: String, Text: text})
+			i += 1 + n
+		case c == '[' && opts.BracketIdents:
+			end := strings.IndexByte(sql[i:], ']')
+			if end < 0 {
+				end = len(sql) - i
+			}
+			tokens = append(tokens, Token{Kind: QuotedIdent, Text: sql[i+1 : i+end]})
+			i = min(i+end+1, len(sql))
+		case c == '"' || c == '`':
+			text, n := scanQuoted(sql[i:], c, opts.BackslashEscapes)
+			tokens = append(tokens, Token{Kind: QuotedIdent, Text: text})
+			i += n
+		case c == '$' && opts.DollarQuotes && dollarTag(sql[i:]) != "":
+			text, n := scanDollarQuoted(sql[i:])
+			tokens = append(tokens, Token{Kind: String, Text: text})
+			i += n
+		case isDigit(c) || (c == '.' && i+1 < len(sql) && isDigit(sql[i+1])):
+			n := scanWhile(sql[i:], func(r rune) bool {
+				return r < utf8.RuneSelf && (isDigit(byte(r)) || isLetter(byte(r)) || r == '.')
+			})
+			tokens = append(tokens, Token{Kind: Number, Text: sql[i : i+n]})
+			i += n
+		case isWordStart(sql[i:]):
+			n := scanWhile(sql[i:], isWordRune)
+			tokens = append(tokens, Token{Kind: Word, Text: sql[i : i+n]})
+			i += n
+		default:
+			_, n := utf8.DecodeRuneInString(sql[i:])
+			tokens = append(tokens, Token{Kind: Symbol, Text: sql[i : i+n]})
+			i += n
+		}
+		if len(tokens) > count {
+			spans = append(spans, span{start: start, end: i})
+		}
+	}
+
+	return tokens, spans
+}
+
+// scanBlockComment returns the length of the block comment at the start of
+// s, which runs to the end of s when unterminated.
+func scanBlockComment(s string, nested bool) int {
+	depth := 0
+	for i := 0; i+1 < len(s); {
+		switch {
+		case s[i] == '/' && s[i+1] == '*' && (nested || depth == 0):
+			depth++
+			i += 2
+		case s[i] == '*' && s[i+1] == '/':
+			depth--
+			i += 2
+			if depth == 0 {
+				return i
+			}
+		default:
+			i++
+		}
+	}
+	return len(s)
+}
+
+// scanQuoted scans a span quoted by q at the start of s, where a doubled q
+// stands for itself. It returns the content and the length of the span; an
+// unterminated span runs to the end of s.
+func scanQuoted(s string, q byte, backslashEscapes bool) (string, int) {
+	var b strings.Builder
+	i := 1
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case backslashEscapes && c == '\\' && i+1 < len(s):
+			b.WriteByte(s[i+1])
+			i += 2
+		case c == q && i+1 < len(s) && s[i+1] == q:
+			b.WriteByte(q)
+			i += 2
+		case c == q:
+			return b.String(), i + 1
+		default:
+			b.WriteByte(c)
+			i++
+		}
+	}
+	return b.String(), i
+}
+
+// dollarTag returns the opening $tag$ at the start of s, or "" if there is
+// none.
+func dollarTag(s string) string {
+	n := 1
+	for n < len(s) && s[n] != '$' {
+		if !isWordRune(rune(s[n])) || isDigit(s[n]) && n == 1 {
+			return ""
+		}
+		n++
+	}
+	if n == len(s) {
+		return ""
+	}
+	return s[:n+1]
+}
+
+func scanDollarQuoted(s string) (string, int) {
+	tag := dollarTag(s)
+	end := strings.Index(s[len(tag):], tag)
+	if end < 0 {
+		return s[len(tag):], len(s)
+	}
+	return s[len(tag) : len(tag)+end], len(tag) + end + len(tag)
+}
+
+func scanWhile(s string, f func(r rune) bool) int {
+	for i, r := range s {
+		if !f(r) {
+			return i
+		}
+	}
+	return len(s)
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f' || c == '\v'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isLetter(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isWordStart(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return r == '_' || r < utf8.RuneSelf && isLetter(byte(r)) || r >= utf8.RuneSelf && unicode.IsLetter(r)
+}
+
+func isWordRune(r rune) bool {
+	if r < utf8.RuneSelf {
+		c := byte(r)
+		return isLetter(c) || isDigit(c) || c == '_' || c == '$'
+	}
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
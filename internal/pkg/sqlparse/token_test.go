@@ -0,0 +1,173 @@
+package sqlparse
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		opts Options
+		want []Token
+	}{
+		{
+			name: "words, numbers and symbols",
+			sql:  "SELECT a.b, 1.5e3 FROM t WHERE x>=.5;",
+			opts: PostgreSQLOptions,
+			want: []Token{
+				{Word, "SELECT"}, {Word, "a"}, {Symbol, "."}, {Word, "b"}, {Symbol, ","}, {Number, "1.5e3"},
+				{Word, "FROM"}, {Word, "t"}, {Word, "WHERE"}, {Word, "x"}, {Symbol, ">"}, {Symbol, "="},
+				{Number, ".5"}, {Symbol, ";"},
+			},
+		},
+		{
+			name: "doubled quotes",
+			sql:  `SELECT 'it''s', "a""b"`,
+			opts: PostgreSQLOptions,
+			want: []Token{{Word, "SELECT"}, {String, "it's"}, {Symbol, ","}, {QuotedIdent, `a"b`}},
+		},
+		{
+			name: "mysql backslash escapes",
+			sql:  `SELECT 'a\'b', ` + "`c\\`d`",
+			opts: MySQLOptions,
+			want: []Token{{Word, "SELECT"}, {String, "a'b"}, {Symbol, ","}, {QuotedIdent, "c`d"}},
+		},
+		{
+			name: "postgresql keeps backslashes",
+			sql:  `SELECT 'a\', b`,
+			opts: PostgreSQLOptions,
+			want: []Token{{Word, "SELECT"}, {String, `a\`}, {Symbol, ","}, {Word, "b"}},
+		},
+		{
+			name: "postgresql escape string",
+			sql:  `SELECT E'a\'b', e'\\'`,
+			opts: PostgreSQLOptions,
+			want: []Token{{Word, "SELECT"}, {String, "a'b"}, {Symbol, ","}, {String, `\`}},
+		},
+		{
+			name: "escape string is a word elsewhere",
+			sql:  `SELECT E'a\'`,
+			opts: SQLiteOptions,
+			want: []Token{{Word, "SELECT"}, {Word, "E"}, {String, `a\`}},
+		},
+		{
+			name: "dollar quotes",
+			sql:  "SELECT $f$it's$f$, $$x$$, $1",
+			opts: PostgreSQLOptions,
+			want: []Token{{Word, "SELECT"}, {String, "it's"}, {Symbol, ","}, {String, "x"}, {Symbol, ","}, {Symbol, "$"}, {Number, "1"}},
+		},
+		{
+			name: "sqlite bracket identifier",
+			sql:  "SELECT [a'b] FROM t",
+			opts: SQLiteOptions,
+			want: []Token{{Word, "SELECT"}, {QuotedIdent, "a'b"}, {Word, "FROM"}, {Word, "t"}},
+		},
+		{
+			name: "dash comment",
+			sql:  "SELECT 1 -- x\nFROM t",
+			opts: PostgreSQLOptions,
+			want: []Token{{Word, "SELECT"}, {Number, "1"}, {Word, "FROM"}, {Word, "t"}},
+		},
+		{
+			name: "dash comment without space",
+			sql:  "SELECT 1 --'\nFROM t",
+			opts: SQLiteOptions,
+			want: []Token{{Word, "SELECT"}, {Number, "1"}, {Word, "FROM"}, {Word, "t"}},
+		},
+		{
+			name: "mysql dash without space",
+			sql:  "SELECT 1--1",
+			opts: MySQLOptions,
+			want: []Token{{Word, "SELECT"}, {Number, "1"}, {Symbol, "-"}, {Symbol, "-"}, {Number, "1"}},
+		},
+		{
+			name: "mysql hash comment",
+			sql:  "SELECT 1 # '\nFROM t",
+			opts: MySQLOptions,
+			want: []Token{{Word, "SELECT"}, {Number, "1"}, {Word, "FROM"}, {Word, "t"}},
+		},
+		{
+			name: "hash is a symbol elsewhere",
+			sql:  "SELECT 1 # 2",
+			opts: PostgreSQLOptions,
+			want: []Token{{Word, "SELECT"}, {Number, "1"}, {Symbol, "#"}, {Number, "2"}},
+		},
+		{
+			name: "block comment",
+			sql:  "SELECT /* ' */ 1",
+			opts: MySQLOptions,
+			want: []Token{{Word, "SELECT"}, {Number, "1"}},
+		},
+		{
+			name: "postgresql nested block comment",
+			sql:  "SELECT /* /* */ ' */ 1",
+			opts: PostgreSQLOptions,
+			want: []Token{{Word, "SELECT"}, {Number, "1"}},
+		},
+		{
+			name: "flat block comment",
+			sql:  "SELECT /* /* */ 1 */",
+			opts: SQLiteOptions,
+			want: []Token{{Word, "SELECT"}, {Number, "1"}, {Symbol, "*"}, {Symbol, "/"}},
+		},
+		{
+			name: "mysql executable comment",
+			sql:  "SELECT /*!50000 1 */",
+			opts: MySQLOptions,
+			want: []Token{{Word, "SELECT"}, {Number, "1"}, {Symbol, "*"}, {Symbol, "/"}},
+		},
+		{
+			name: "executable comment is a comment elsewhere",
+			sql:  "SELECT /*! ' */ 1",
+			opts: PostgreSQLOptions,
+			want: []Token{{Word, "SELECT"}, {Number, "1"}},
+		},
+		{
+			name: "unterminated",
+			sql:  "SELECT 'a",
+			opts: PostgreSQLOptions,
+			want: []Token{{Word, "SELECT"}, {String, "a"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Tokenize(tt.sql, tt.opts)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tokenize(%q) = %v, want %v", tt.sql, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		name string
+		sql  string
+		opts Options
+		want []string
+	}{
+		{name: "statements", sql: "SELECT 1; ; DROP TABLE t;", opts: PostgreSQLOptions, want: []string{"SELECT", "DROP"}},
+		{name: "with", sql: "WITH a AS (SELECT 1) DELETE FROM t", opts: PostgreSQLOptions, want: []string{"DELETE"}},
+		{name: "semicolon in string", sql: "SELECT ';DROP TABLE t'", opts: PostgreSQLOptions, want: []string{"SELECT"}},
+		{name: "mysql hash comment", sql: "SELECT 1 # '\nFROM t; DROP TABLE x; -- '", opts: MySQLOptions, want: []string{"SELECT", "DROP"}},
+		{name: "postgresql dash comment", sql: "SELECT 1 --'\n; DROP TABLE x; --'", opts: PostgreSQLOptions, want: []string{"SELECT", "DROP"}},
+		{name: "postgresql nested comment", sql: "SELECT 1 /* /* */ ' */; DROP TABLE x; --'", opts: PostgreSQLOptions, want: []string{"SELECT", "DROP"}},
+		{name: "postgresql escape string", sql: "SELECT E'\\''; DROP TABLE x; --'", opts: PostgreSQLOptions, want: []string{"SELECT", "DROP"}},
+		{name: "sqlite bracket identifier", sql: "SELECT 1 AS [']; DROP TABLE x; --[']", opts: SQLiteOptions, want: []string{"SELECT", "DROP"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, statement := range Split(tt.sql, tt.opts) {
+				got = append(got, statement.Kind())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Split(%q) kinds = %v, want %v", tt.sql, got, tt.want)
+			}
+		})
+	}
+}